@@ -1,37 +1,52 @@
 package confire
 
 import (
-	"go.rtnl.ai/confire/defaults"
+	"context"
+	"strconv"
+	"strings"
+
 	"go.rtnl.ai/confire/env"
+	confireErrors "go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/source"
 	"go.rtnl.ai/confire/validate"
 )
 
-// Process is the main entry point to configuring and validating a struct from defaults
-// and the environment. Pass in a prefix for environment variables and a pointer to the
-// configuration struct you want processed (as well as any options). The processor will
-// first populate the struct with defaults, then load any values found in the
+// Process is the main entry point to configuring and validating a struct from defaults,
+// configuration files, and the environment. Pass in a prefix for environment variables
+// and a pointer to the configuration struct you want processed (as well as any
+// options). The processor will first populate the struct with defaults, then load the
+// configuration file if one is specified, then load any values found in the
 // environment, finally validating the struct based on struct tags and the validate
 // interface. A ParseError or a ValidationError may be returned if not successful.
+//
+// The order of the sources that are loaded can be changed (and other sources added)
+// with the WithSources option.
 func Process(prefix string, spec interface{}, opts ...Option) (err error) {
+	return ProcessContext(context.Background(), prefix, spec, opts...)
+}
+
+// ProcessContext is the same as Process but passes the context to each of the sources
+// that are loaded, stopping early if the context is canceled.
+func ProcessContext(ctx context.Context, prefix string, spec interface{}, opts ...Option) (err error) {
 	var opt *options
 	if opt, err = makeOptions(opts...); err != nil {
 		return err
 	}
 
-	if !opt.noDefaults {
-		if err = defaults.Process(spec); err != nil {
-			return err
-		}
+	gathered := source.New(prefix)
+	if opt.origins != nil {
+		gathered.Origins = opt.origins
 	}
 
-	if !opt.noEnv {
-		if err = env.Process(prefix, spec); err != nil {
-			return err
-		}
+	if err = source.Load(ctx, spec, gathered, opt.pipeline(prefix)...); err != nil {
+		return err
 	}
 
 	if !opt.noValidate {
 		if err = validate.Validate(spec); err != nil {
+			if !opt.noEnv {
+				err = withEnvKeys(prefix, spec, err)
+			}
 			return err
 		}
 	}
@@ -39,9 +54,103 @@ func Process(prefix string, spec interface{}, opts ...Option) (err error) {
 	return nil
 }
 
+// Adds the environment variable that sets each invalid field to the validation errors,
+// e.g. so that users can be told to set MYAPP_DATABASE_PORT to fix the configuration.
+func withEnvKeys(prefix string, spec interface{}, err error) error {
+	infos, gerr := env.Gather(prefix, spec)
+	if gerr != nil {
+		return err
+	}
+
+	keys := make(map[string]string, len(infos))
+	for _, info := range infos {
+		keys[info.Path] = info.Key
+	}
+
+	// Elements of slices and maps such as Peers[3] are set by the variable of the field
+	// unless they are structs that have variables for each of their fields. The fields of
+	// maps of structs are gathered as a pattern, e.g. PREFIX_DATABASES_<KEY>_HOST, so the
+	// key of the entry is substituted into the pattern.
+	lookup := func(path string) (string, bool) {
+		for {
+			if key, ok := keys[path]; ok {
+				return key, true
+			}
+
+			pattern, entries := mapEntries(path)
+			if key, ok := keys[pattern]; ok && len(entries) > 0 {
+				for _, entry := range entries {
+					key = strings.Replace(key, "<KEY>", strings.ToUpper(entry), 1)
+				}
+				return key, true
+			}
+
+			idx := strings.LastIndexByte(path, '[')
+			if idx < 0 || !strings.HasSuffix(path, "]") {
+				return "", false
+			}
+			path = path[:idx]
+		}
+	}
+
+	switch verr := err.(type) {
+	case *confireErrors.InvalidConfig:
+		if key, ok := lookup(verr.Field()); ok {
+			return verr.WithKey(key)
+		}
+	case confireErrors.ValidationErrors:
+		for i, e := range verr {
+			if key, ok := lookup(e.Field()); ok {
+				verr[i] = e.WithKey(key)
+			}
+		}
+	}
+	return err
+}
+
+// Replaces the keys of map entries in the path with the <KEY> placeholder gathered for
+// maps of structs, e.g. Databases[primary].Host becomes Databases[<KEY>].Host, returning
+// the keys in the order they were replaced. Indices of slices are not replaced.
+func mapEntries(path string) (pattern string, entries []string) {
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(path, '[')
+		if start < 0 {
+			break
+		}
+
+		end := strings.IndexByte(path[start:], ']')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		entry := path[start+1 : end]
+		sb.WriteString(path[:start+1])
+		if _, err := strconv.Atoi(entry); err != nil && entry != "<KEY>" {
+			entries = append(entries, entry)
+			entry = "<KEY>"
+		}
+		sb.WriteString(entry)
+		sb.WriteByte(']')
+		path = path[end+1:]
+	}
+	sb.WriteString(path)
+	return sb.String(), entries
+}
+
 // MustProcess panics if processing the specification results in an error.
 func MustProcess(prefix string, spec interface{}, opts ...Option) {
 	if err := Process(prefix, spec, opts...); err != nil {
 		panic(err)
 	}
 }
+
+// Bringing in the source types from the source package for convenience.
+type (
+	Source     = source.Source
+	SourceFunc = source.Func
+	Gathered   = source.Gathered
+	Origin     = source.Origin
+	Origins    = source.Origins
+)
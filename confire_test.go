@@ -1,7 +1,12 @@
 package confire_test
 
 import (
+	"context"
 	"encoding/hex"
+	"flag"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -9,6 +14,10 @@ import (
 	"go.rtnl.ai/confire"
 	"go.rtnl.ai/confire/assert"
 	"go.rtnl.ai/confire/contest"
+	"go.rtnl.ai/confire/defaults"
+	"go.rtnl.ai/confire/env"
+	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/flags"
 )
 
 //============================================================================
@@ -184,6 +193,502 @@ func TestConfig(t *testing.T) {
 	assert.Equals(t, validConfig, conf)
 }
 
+func TestConfigFile(t *testing.T) {
+	env := contest.Env{
+		"CONFIRE_PORT": "8000",
+		"DATABASE_URL": "sqlite://myapp.db",
+	}
+	t.Cleanup(testEnv.Clear())
+	t.Cleanup(env.Set())
+
+	var conf Config
+	err := confire.Process("confire", &conf, confire.WithConfigFile("testdata/config.yaml"))
+	assert.Ok(t, err)
+
+	// Values from the file override the defaults
+	assert.Equals(t, "fromfile", conf.ServiceName)
+	assert.Equals(t, "10.0.0.1", conf.Host)
+	assert.False(t, conf.UI.Enabled)
+
+	// Values from the environment override the file
+	assert.Equals(t, 8000, conf.Port)
+	assert.Equals(t, "sqlite://myapp.db", conf.Database.URL)
+
+	// Defaults are kept when not in the file or the environment
+	assert.Equals(t, 10*time.Second, conf.Timeout)
+	assert.Equals(t, LevelInfo, conf.LogLevel)
+}
+
+func TestConfigSearch(t *testing.T) {
+	env := contest.Env{
+		"CONFIRE_CONFIG":     "",
+		"DATABASE_URL":       "sqlite://myapp.db",
+		"CONFIRE_UI_ENABLED": "false",
+	}
+	t.Cleanup(testEnv.Clear())
+	t.Cleanup(env.Set())
+
+	dirA, dirB := t.TempDir(), t.TempDir()
+	assert.Ok(t, os.WriteFile(filepath.Join(dirA, "confire.json"), []byte(`{"service_name": "json", "port": 2000}`), 0600))
+	assert.Ok(t, os.WriteFile(filepath.Join(dirB, "confire.toml"), []byte("service_name = \"toml\""), 0600))
+	assert.Ok(t, os.WriteFile(filepath.Join(dirB, "myapp.yaml"), []byte("service_name: yaml"), 0600))
+
+	t.Run("SearchPaths", func(t *testing.T) {
+		var conf Config
+		err := confire.Process("confire", &conf, confire.WithSearchPaths(dirB, dirA))
+		assert.Ok(t, err)
+		assert.Equals(t, "toml", conf.ServiceName)
+		assert.Equals(t, 8080, conf.Port)
+
+		conf = Config{}
+		err = confire.Process("confire", &conf, confire.WithSearchPaths(dirA, dirB))
+		assert.Ok(t, err)
+		assert.Equals(t, "json", conf.ServiceName)
+		assert.Equals(t, 2000, conf.Port)
+	})
+
+	t.Run("ConfigName", func(t *testing.T) {
+		var conf Config
+		err := confire.Process("confire", &conf, confire.WithConfigName("myapp"), confire.WithSearchPaths(dirA, dirB))
+		assert.Ok(t, err)
+		assert.Equals(t, "yaml", conf.ServiceName)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		var conf Config
+		err := confire.Process("confire", &conf, confire.WithConfigName("notfound"), confire.WithSearchPaths(dirA, dirB))
+		assert.NotOk(t, err)
+		assert.True(t, confire.IsInvalidConfig(err))
+	})
+
+	t.Run("EnvOverride", func(t *testing.T) {
+		t.Setenv("CONFIRE_CONFIG", filepath.Join(dirB, "myapp.yaml"))
+
+		var conf Config
+		err := confire.Process("confire", &conf, confire.WithConfigFile(filepath.Join(dirA, "confire.json")))
+		assert.Ok(t, err)
+		assert.Equals(t, "yaml", conf.ServiceName)
+	})
+
+	t.Run("NoSearch", func(t *testing.T) {
+		t.Setenv("CONFIRE_CONFIG", filepath.Join(dirB, "myapp.yaml"))
+
+		var conf Config
+		err := confire.Process("confire", &conf)
+		assert.NotOk(t, err)
+		assert.True(t, confire.IsInvalidConfig(err))
+	})
+}
+
+func TestSources(t *testing.T) {
+	t.Cleanup(testEnv.Set())
+
+	// A custom source that sets values from a "secret store"
+	secrets := confire.SourceFunc(func(_ context.Context, spec interface{}, g *confire.Gathered) error {
+		assert.Equals(t, "confire", g.Prefix)
+		conf := spec.(*Config)
+		conf.Database.URL = "postgres://secret@localhost/myapp"
+		conf.Port = 4443
+		return nil
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		var conf Config
+		err := confire.Process("confire", &conf, confire.WithSources(defaults.Source{}, env.Source{}, secrets))
+		assert.Ok(t, err)
+
+		expected := validConfig
+		expected.Database.URL = "postgres://secret@localhost/myapp"
+		expected.Port = 4443
+		assert.Equals(t, expected, conf)
+	})
+
+	t.Run("Order", func(t *testing.T) {
+		var conf Config
+		err := confire.Process("confire", &conf, confire.WithSources(secrets, env.Source{}, defaults.Source{}), confire.NoValidate)
+		assert.Ok(t, err)
+
+		// Defaults override the environment and the secrets
+		assert.Equals(t, "0.0.0.0", conf.Host)
+		assert.Equals(t, 8080, conf.Port)
+		assert.Equals(t, "sqlite://myapp.db", conf.Database.URL)
+		assert.Equals(t, "myapp", conf.ServiceName)
+	})
+
+	t.Run("Conflicts", func(t *testing.T) {
+		// Options that configure the default sources are not silently ignored
+		var conf Config
+		err := confire.Process("confire", &conf, confire.Strict, confire.WithSources(defaults.Source{}, env.Source{}), confire.WithConfigFile("config.yaml"))
+		assert.ErrorIs(t, err, errors.ErrConflictingOptions)
+		assert.Equals(t, "confire: cannot use Strict, WithConfigFile with WithSources: options configure the default sources", err.Error())
+
+		_, err = confire.Watch("confire", &conf, confire.WithSources(env.Source{}), confire.NoEnv)
+		assert.ErrorIs(t, err, errors.ErrConflictingOptions)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var conf Config
+		err := confire.ProcessContext(ctx, "confire", &conf)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestFlags(t *testing.T) {
+	t.Cleanup(testEnv.Set())
+
+	var conf Config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	assert.Ok(t, flags.Register(fs, &conf))
+	assert.Ok(t, fs.Parse([]string{"-port", "4443", "-database-read-only=false"}))
+
+	// Flags take precedence over the environment
+	err := confire.Process("confire", &conf, confire.WithFlags(fs))
+	assert.Ok(t, err)
+
+	expected := validConfig
+	expected.Port = 4443
+	expected.Database.ReadOnly = false
+	assert.Equals(t, expected, conf)
+}
+
+func TestOrigins(t *testing.T) {
+	env := contest.Env{
+		"CONFIRE_PORT": "8000",
+		"DATABASE_URL": "sqlite://myapp.db",
+	}
+	t.Cleanup(testEnv.Clear())
+	t.Cleanup(env.Set())
+
+	var conf Config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	assert.Ok(t, flags.Register(fs, &conf))
+	assert.Ok(t, fs.Parse([]string{"-host", "192.168.1.1"}))
+
+	origins := make(confire.Origins)
+	err := confire.Process("confire", &conf, confire.WithConfigFile("testdata/config.yaml"), confire.WithFlags(fs), confire.WithOrigins(origins))
+	assert.Ok(t, err)
+
+	assert.Equals(t, confire.Origin{Kind: "default", Value: "10s"}, origins["Timeout"])
+	assert.Equals(t, confire.Origin{Kind: "file", Location: "testdata/config.yaml:1", Value: "fromfile"}, origins["ServiceName"])
+	assert.Equals(t, confire.Origin{Kind: "file", Location: "testdata/config.yaml:7", Value: "false"}, origins["UI.Enabled"])
+	assert.Equals(t, confire.Origin{Kind: "env", Location: "CONFIRE_PORT", Value: "8000"}, origins["Port"])
+	assert.Equals(t, confire.Origin{Kind: "env", Location: "DATABASE_URL", Value: "sqlite://myapp.db"}, origins["Database.URL"])
+	assert.Equals(t, confire.Origin{Kind: "flag", Location: "-host", Value: "192.168.1.1"}, origins["Host"])
+	assert.Equals(t, `env CONFIRE_PORT="8000"`, origins["Port"].String())
+
+	// Fields that were not set by any source are not recorded
+	_, ok := origins["UI.Palette"]
+	assert.False(t, ok)
+}
+
+func TestInterpolation(t *testing.T) {
+	type InterpolatedConfig struct {
+		Host    string `default:"localhost"`
+		Port    int    `default:"8080"`
+		URL     string `default:"http://${Host}:${Port}"`
+		DataDir string `split_words:"true" default:"${CONFIRE_TEST_HOME:-/var/lib}/myapp"`
+	}
+
+	t.Cleanup(contest.Env{"CONFIRE_HOST": "example.com", "CONFIRE_TEST_HOME": "/home/confire"}.Set())
+
+	var conf InterpolatedConfig
+	err := confire.Process("confire", &conf, confire.WithInterpolation())
+	assert.Ok(t, err)
+
+	// References in defaults resolve to the other defaults, not the environment
+	assert.Equals(t, "example.com", conf.Host)
+	assert.Equals(t, "http://localhost:8080", conf.URL)
+	assert.Equals(t, "/home/confire/myapp", conf.DataDir)
+}
+
+func TestLookuper(t *testing.T) {
+	t.Cleanup(testEnv.Clear())
+
+	t.Run("Isolated", func(t *testing.T) {
+		t.Parallel()
+
+		var conf Config
+		err := confire.Process("confire", &conf, confire.WithLookuper(env.MapLookuper(testEnv)))
+		assert.Ok(t, err)
+		assert.Equals(t, validConfig, conf)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		t.Parallel()
+
+		vars := env.MapLookuper{
+			"CONFIRE_CONFIG":       "testdata/config.yaml",
+			"DATABASE_URL":         "sqlite://myapp.db",
+			"CONFIRE_SERVICE_NAME": "${CONFIRE_TEST_NAME:-lookup}",
+		}
+
+		var conf Config
+		err := confire.Process("confire", &conf, confire.WithLookuper(vars), confire.WithConfigName("missing"), confire.WithInterpolation())
+		assert.Ok(t, err)
+		assert.Equals(t, "lookup", conf.ServiceName)
+		assert.Equals(t, "10.0.0.1", conf.Host)
+		assert.Equals(t, 9000, conf.Port)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		t.Parallel()
+
+		// The configuration directory is looked up rather than read from the environment
+		home := t.TempDir()
+		assert.Ok(t, os.Mkdir(filepath.Join(home, "confire"), 0700))
+		assert.Ok(t, os.WriteFile(filepath.Join(home, "confire", "myapp.yaml"), []byte("service_name: xdg"), 0600))
+
+		vars := env.MapLookuper{
+			"XDG_CONFIG_HOME":    home,
+			"DATABASE_URL":       "sqlite://myapp.db",
+			"CONFIRE_UI_ENABLED": "false",
+		}
+
+		var conf Config
+		err := confire.Process("confire", &conf, confire.WithLookuper(vars), confire.WithConfigName("myapp"))
+		assert.Ok(t, err)
+		assert.Equals(t, "xdg", conf.ServiceName)
+	})
+}
+
+func TestStrict(t *testing.T) {
+	vars := make(env.MapLookuper, len(testEnv)+1)
+	for key, val := range testEnv {
+		vars[key] = val
+	}
+
+	var conf Config
+	assert.Ok(t, confire.Process("confire", &conf, confire.WithLookuper(vars), confire.Strict))
+
+	vars["CONFIRE_SERVICE_NAM"] = "typo"
+	err := confire.Process("confire", &conf, confire.WithLookuper(vars), confire.Strict)
+	assert.ErrorIs(t, err, errors.ErrUnknownKey)
+	assert.Equals(t, "confire: unknown environment variables: CONFIRE_SERVICE_NAM (did you mean CONFIRE_SERVICE_NAME?)", err.Error())
+
+	// Unknown variables are ignored unless strict
+	assert.Ok(t, confire.Process("confire", &conf, confire.WithLookuper(vars)))
+}
+
+func TestProcessParseErrors(t *testing.T) {
+	vars := make(env.MapLookuper, len(testEnv)+2)
+	for key, val := range testEnv {
+		vars[key] = val
+	}
+	vars["CONFIRE_PORT"] = "http"
+	vars["CONFIRE_DEBUG"] = "maybe"
+
+	// All of the invalid environment variables are reported
+	var conf Config
+	err := confire.Process("confire", &conf, confire.WithLookuper(vars))
+	assert.True(t, confire.IsParseError(err))
+
+	errs, ok := confire.ParseErrors(err)
+	assert.True(t, ok)
+	assert.Equals(t, 2, len(errs))
+	assert.Equals(t, "CONFIRE_DEBUG", errs[0].Source)
+	assert.Equals(t, "CONFIRE_PORT", errs[1].Source)
+
+	perr, ok := confire.ParseError(err)
+	assert.True(t, ok)
+	assert.Equals(t, errs[0], perr)
+}
+
+func TestSlices(t *testing.T) {
+	type PeerConfig struct {
+		Host string `required:"true"`
+		Port int    `default:"4000"`
+	}
+
+	type ClusterConfig struct {
+		Peers []PeerConfig
+	}
+
+	vars := env.MapLookuper{
+		"CONFIRE_PEERS_0_HOST": "alpha",
+		"CONFIRE_PEERS_1_HOST": "bravo",
+		"CONFIRE_PEERS_1_PORT": "4001",
+	}
+
+	origins := make(confire.Origins)
+	var conf ClusterConfig
+	err := confire.Process("confire", &conf, confire.WithLookuper(vars), confire.WithOrigins(origins))
+	assert.Ok(t, err)
+	assert.Equals(t, []PeerConfig{{Host: "alpha", Port: 4000}, {Host: "bravo", Port: 4001}}, conf.Peers)
+	assert.Equals(t, "CONFIRE_PEERS_1_PORT", origins["Peers[1].Port"].Location)
+
+	// Every element is validated
+	vars["CONFIRE_PEERS_3_PORT"] = "4003"
+	conf = ClusterConfig{}
+	err = confire.Process("confire", &conf, confire.WithLookuper(vars))
+	assert.True(t, confire.IsValidationErrors(err))
+}
+
+func TestRegisteredTypes(t *testing.T) {
+	type Config struct {
+		Endpoint url.URL
+		Callback *url.URL
+		Fallback *url.URL
+		Unset    url.URL
+		Zone     *time.Location
+		NoZone   *time.Location
+	}
+
+	vars := env.MapLookuper{
+		"CONFIRE_ENDPOINT": "https://example.com/api",
+		"CONFIRE_CALLBACK": "https://example.com/callback",
+		"CONFIRE_ZONE":     "America/New_York",
+	}
+
+	// Registered types are set as single values rather than walked as nested structs
+	var conf Config
+	err := confire.Process("confire", &conf, confire.WithLookuper(vars))
+	assert.Ok(t, err)
+	assert.Equals(t, "https://example.com/api", conf.Endpoint.String())
+	assert.Equals(t, "https://example.com/callback", conf.Callback.String())
+	assert.True(t, conf.Endpoint.User == nil)
+	assert.True(t, conf.Fallback == nil)
+	assert.Equals(t, "", conf.Unset.String())
+	assert.Equals(t, "America/New_York", conf.Zone.String())
+	assert.True(t, conf.NoZone == nil)
+}
+
+func TestValidationKeys(t *testing.T) {
+	type PeerConfig struct {
+		Host string `required:"true"`
+	}
+
+	type ClusterConfig struct {
+		Endpoints []string `validate:"dive,url"`
+		Peers     []PeerConfig
+	}
+
+	vars := env.MapLookuper{
+		"CONFIRE_ENDPOINTS":    "https://a.example.com,b.example.com",
+		"CONFIRE_PEERS_1_HOST": "bravo",
+	}
+
+	var conf ClusterConfig
+	err := confire.Process("confire", &conf, confire.WithLookuper(vars))
+
+	errs, ok := confire.ValidationErrors(err)
+	assert.True(t, ok)
+	assert.Equals(t, 2, len(errs))
+
+	// Elements are set by the variable of the field unless they are structs
+	assert.Equals(t, "Endpoints[1]", errs[0].Field())
+	assert.Equals(t, "CONFIRE_ENDPOINTS", errs[0].Key())
+	assert.Equals(t, "Peers[0].Host", errs[1].Field())
+	assert.Equals(t, "CONFIRE_PEERS_0_HOST", errs[1].Key())
+
+	t.Run("Maps", func(t *testing.T) {
+		type DBConfig struct {
+			Host string `required:"true"`
+			Port int    `validate:"max=65535"`
+		}
+
+		type MultiConfig struct {
+			Databases map[string]DBConfig
+		}
+
+		vars := env.MapLookuper{
+			"CONFIRE_DATABASES_PRIMARY_PORT": "5432",
+			"CONFIRE_DATABASES_REPLICA_HOST": "db2",
+			"CONFIRE_DATABASES_REPLICA_PORT": "70000",
+		}
+
+		var conf MultiConfig
+		err := confire.Process("confire", &conf, confire.WithLookuper(vars))
+
+		errs, ok := confire.ValidationErrors(err)
+		assert.True(t, ok)
+		assert.Equals(t, 2, len(errs))
+
+		// The fields of map entries are set by the variable with the key of the entry
+		assert.Equals(t, "Databases[primary].Host", errs[0].Field())
+		assert.Equals(t, "CONFIRE_DATABASES_PRIMARY_HOST", errs[0].Key())
+		assert.Equals(t, "Databases[replica].Port", errs[1].Field())
+		assert.Equals(t, "CONFIRE_DATABASES_REPLICA_PORT", errs[1].Key())
+	})
+}
+
+func TestMaps(t *testing.T) {
+	type DBConfig struct {
+		Host string `required:"true"`
+		Port int    `default:"5432"`
+	}
+
+	type MultiConfig struct {
+		Databases map[string]DBConfig
+	}
+
+	vars := env.MapLookuper{
+		"CONFIRE_DATABASES_PRIMARY_HOST": "db1",
+		"CONFIRE_DATABASES_REPLICA_HOST": "db2",
+		"CONFIRE_DATABASES_REPLICA_PORT": "5433",
+	}
+
+	var conf MultiConfig
+	err := confire.Process("confire", &conf, confire.WithLookuper(vars), confire.Strict)
+	assert.Ok(t, err)
+	assert.Equals(t, map[string]DBConfig{"primary": {Host: "db1", Port: 5432}, "replica": {Host: "db2", Port: 5433}}, conf.Databases)
+}
+
+func TestSecrets(t *testing.T) {
+	type SecretConfig struct {
+		APIKey string `secret:"true" split_words:"true"`
+		Salt   int    `secret:"true"`
+	}
+
+	t.Run("Origins", func(t *testing.T) {
+		t.Cleanup(contest.Env{"CONFIRE_API_KEY": "sk-supersecret"}.Set())
+
+		var conf SecretConfig
+		origins := make(confire.Origins)
+		err := confire.Process("confire", &conf, confire.WithOrigins(origins))
+		assert.Ok(t, err)
+
+		assert.Equals(t, "sk-supersecret", conf.APIKey)
+		assert.Equals(t, `env CONFIRE_API_KEY="******"`, origins["APIKey"].String())
+	})
+
+	t.Run("ParseError", func(t *testing.T) {
+		t.Cleanup(contest.Env{"CONFIRE_SALT": "sk-supersecret"}.Set())
+
+		var conf SecretConfig
+		err := confire.Process("confire", &conf)
+		assert.NotOk(t, err)
+		assert.Equals(t, `confire: could not parse Salt from CONFIRE_SALT: converting "******" to type int: invalid syntax`, err.Error())
+	})
+
+	t.Run("Interpolation", func(t *testing.T) {
+		// Values that are interpolated from secret fields are secret themselves
+		type InterpolatedConfig struct {
+			Password string `secret:"true" default:"hunter2"`
+			DSN      string `default:"postgres://u:${Password}@db"`
+			Port     int
+		}
+
+		t.Cleanup(contest.Env{"CONFIRE_PORT": "${DSN}"}.Set())
+
+		var conf InterpolatedConfig
+		origins := make(confire.Origins)
+		err := confire.Process("confire", &conf, confire.WithInterpolation(), confire.WithOrigins(origins))
+		assert.NotOk(t, err)
+		assert.Equals(t, `confire: could not parse Port from CONFIRE_PORT: converting "******" to type int: invalid syntax`, err.Error())
+		assert.Equals(t, `default "******"`, origins["DSN"].String())
+		assert.True(t, origins["DSN"].Secret)
+
+		t.Cleanup(contest.Env{"CONFIRE_PORT": "5432"}.Set())
+		err = confire.Process("confire", &conf, confire.WithInterpolation(), confire.WithOrigins(origins))
+		assert.Ok(t, err)
+		assert.Equals(t, "postgres://u:hunter2@db", conf.DSN)
+		assert.Equals(t, `env CONFIRE_PORT="5432"`, origins["Port"].String())
+	})
+}
+
 func TestValidation(t *testing.T) {
 
 	t.Run("Defaults", func(t *testing.T) {
@@ -198,10 +703,15 @@ func TestValidation(t *testing.T) {
 		assert.True(t, ok)
 		assert.Assert(t, len(errs) == 5, "expected 5 validation errors got %d", len(errs))
 		assert.Equals(t, "invalid configuration: ServiceName is required but not set", errs[0].Error())
-		assert.Equals(t, "invalid configuration: URL is required but not set", errs[1].Error())
+		assert.Equals(t, "invalid configuration: Database.URL is required but not set", errs[1].Error())
 		assert.Equals(t, "invalid configuration: ui.palette is required but not set", errs[2].Error())
 		assert.Equals(t, "invalid configuration: ui.palette primary color must be included in the palette", errs[3].Error())
 		assert.Equals(t, "invalid configuration: ui.palette secondary color must be included in the palette", errs[4].Error())
+
+		// The environment variable that sets each field is reported if known
+		assert.Equals(t, "CONFIRE_SERVICE_NAME", errs[0].Key())
+		assert.Equals(t, "CONFIRE_DATABASE_DATABASE_URL", errs[1].Key())
+		assert.Equals(t, "", errs[2].Key())
 	})
 
 	t.Run("SoWrong", func(t *testing.T) {
@@ -226,7 +736,7 @@ func TestValidation(t *testing.T) {
 		assert.Equals(t, "invalid configuration: port must be in the integer range [1024, 65535]", errs[0].Error())
 		assert.Equals(t, "invalid configuration: rate must be in the float range [0.0, 1.0]", errs[1].Error())
 		assert.Equals(t, "invalid configuration: ServiceName is required but not set", errs[2].Error())
-		assert.Equals(t, "invalid configuration: URL is required but not set", errs[3].Error())
+		assert.Equals(t, "invalid configuration: Database.URL is required but not set", errs[3].Error())
 		assert.Equals(t, "invalid configuration: ui.palette primary color must be included in the palette", errs[4].Error())
 		assert.Equals(t, "invalid configuration: ui.palette secondary color must be included in the palette", errs[5].Error())
 	})
@@ -6,10 +6,14 @@ that environment variables from the confire env package are parsed.
 package defaults
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 
 	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/interpolate"
 	"go.rtnl.ai/confire/parse"
+	"go.rtnl.ai/confire/source"
 	"go.rtnl.ai/confire/structs"
 )
 
@@ -20,14 +24,84 @@ const tagDefault = "default"
 // variables. Most types are parsed using the strconv package (time.Duration is handled
 // specially). If the type implements Decoder, Setter, TextUnmarshaler, or
 // BinaryUnmarshaler, then those decoders are used to parse the default value.
-func Process(spec interface{}) (err error) {
+func Process(spec interface{}, opts ...Option) error {
+	return process(spec, nil, opts...)
+}
+
+// Process the defaults, recording the origin of each default using the dotted path of
+// the field with the gathered state (if any).
+func process(spec interface{}, gathered *source.Gathered, opts ...Option) (err error) {
+	var opt *options
+	if opt, err = makeOptions(opts...); err != nil {
+		return err
+	}
+
+	var infos []info
+	if infos, err = gather("", spec); err != nil {
+		return err
+	}
+
+	var interp *interpolate.Interpolator
+	if opt.interpolate {
+		// The process environment is used if no lookuper is specified
+		var lookup func(string) (string, bool)
+		if opt.lookuper != nil {
+			lookup = opt.lookuper.Lookup
+		}
+
+		if interp, err = interpolate.New(spec, lookup); err != nil {
+			return err
+		}
+
+		for _, info := range infos {
+			interp.Set(info.path, info.value)
+		}
+	}
+
+	for _, info := range infos {
+		value := info.value
+		if interp != nil {
+			if value, err = interp.Field(info.path); err != nil {
+				return &errors.InterpolationError{Source: tagDefault, Field: info.field.Name(), Err: err}
+			}
+		}
+
+		// Values interpolated from secret fields are as secret as the fields themselves
+		secret := interp != nil && interp.Secret(info.path)
+		if err = parse.ParseField(value, info.field); err != nil {
+			if secret {
+				return parse.MaskError(err)
+			}
+			return err
+		}
+
+		origin := source.Origin{Kind: source.Default, Value: parse.RedactValue(info.field, value)}
+		if secret && value != "" {
+			origin.Value, origin.Secret = parse.Mask, true
+		}
+		gathered.Set(info.path, origin)
+	}
+
+	return nil
+}
+
+// info is a field that has a default value along with its dotted path in the spec.
+type info struct {
+	path  string
+	value string
+	field *structs.Field
+}
+
+// Gather the fields that have default values, recursively gathering nested structs
+// that do not have a default value themselves.
+func gather(path string, spec interface{}) (infos []info, err error) {
 	var s *structs.Struct
 	if s, err = structs.New(spec); err != nil {
-		return errors.ErrInvalidSpecification
+		return nil, errors.ErrInvalidSpecification
 	}
 
 	if !s.IsPointer() {
-		return errors.ErrInvalidSpecification
+		return nil, errors.ErrInvalidSpecification
 	}
 
 	for _, field := range s.Fields() {
@@ -37,10 +111,10 @@ func Process(spec interface{}) (err error) {
 		}
 
 		// Handle pointers if necessary
-		for field.Kind() == reflect.Ptr {
+		for field.Kind() == reflect.Ptr && !parse.Registered(field.Type()) {
 			if field.IsNil() {
-				if field.TypeKind() != reflect.Struct {
-					// nil pointer to a non-struct: leave it alone.
+				if field.TypeKind() != reflect.Struct || parse.IsDecodable(field) {
+					// nil pointer to a non-struct or to a decodable struct: leave it alone.
 					break
 				}
 
@@ -54,26 +128,66 @@ func Process(spec interface{}) (err error) {
 
 		// Check if this field has a default value
 		if value := field.Tag(tagDefault); value != "" {
-			if err = parse.ParseField(value, field); err != nil {
-				return err
+			infos = append(infos, info{path: join(path, field.Name()), value: value, field: field})
+		} else if field.Kind() == reflect.Struct && !parse.IsDecodable(field) {
+			// Embedded structs are referred to by their promoted field names
+			nestedPath := path
+			if !field.IsEmbedded() {
+				nestedPath = join(path, field.Name())
+			}
+
+			var nested []info
+			if nested, err = gather(nestedPath, field.Pointer()); err != nil {
+				return nil, err
 			}
-		} else if field.Kind() == reflect.Struct {
-			if err = Process(field.Pointer()); err != nil {
-				return err
+			infos = append(infos, nested...)
+		} else if parse.IsStructSlice(field) {
+			// Each element of a slice of structs gets the defaults of the struct
+			for i := 0; i < field.Len(); i++ {
+				elem := field.Index(i)
+				spec := elem.Value()
+				if elem.Kind() != reflect.Ptr {
+					spec = elem.Pointer()
+				}
+
+				var nested []info
+				if nested, err = gather(fmt.Sprintf("%s[%d]", join(path, field.Name()), i), spec); err != nil {
+					return nil, err
+				}
+				infos = append(infos, nested...)
 			}
 		}
 
 	}
 
-	return nil
+	return infos, nil
 }
 
 // MustProcess is the same as Process but panics if an error occurs
-func MustProcess(spec interface{}) {
-	if err := Process(spec); err != nil {
+func MustProcess(spec interface{}, opts ...Option) {
+	if err := Process(spec, opts...); err != nil {
 		panic(err)
 	}
 }
 
+// Source loads the defaults from the default struct tags as a configuration source.
+type Source struct {
+	Options []Option // Any options for processing the defaults
+}
+
+var _ source.Source = Source{}
+
+// Load implements the source.Source interface by processing the defaults.
+func (s Source) Load(_ context.Context, spec interface{}, gathered *source.Gathered) error {
+	return process(spec, gathered, s.Options...)
+}
+
 // SetDefaults is an alias of Process
-var SetDefaults func(spec interface{}) error = Process
+var SetDefaults func(spec interface{}, opts ...Option) error = Process
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -7,6 +7,7 @@ import (
 
 	"go.rtnl.ai/confire/assert"
 	"go.rtnl.ai/confire/defaults"
+	cerrors "go.rtnl.ai/confire/errors"
 )
 
 type Specification struct {
@@ -102,3 +103,65 @@ func TestDefaults(t *testing.T) {
 	assert.Equals(t, time.Duration(0), spec.NoDefaultDuration)
 
 }
+
+func TestSlices(t *testing.T) {
+	type SliceSpec struct {
+		Peers []Embedded
+		Ptrs  []*Embedded
+	}
+
+	// Each of the existing elements gets the defaults
+	spec := SliceSpec{Peers: make([]Embedded, 2), Ptrs: []*Embedded{nil}}
+	err := defaults.Process(&spec)
+	assert.Ok(t, err)
+
+	expected := Embedded{Enabled: true, EmPort: 443, Langs: []string{"en", "fr"}}
+	assert.Equals(t, []Embedded{expected, expected}, spec.Peers)
+	assert.Equals(t, []*Embedded{&expected}, spec.Ptrs)
+
+	// Empty slices are not grown
+	spec = SliceSpec{}
+	err = defaults.Process(&spec)
+	assert.Ok(t, err)
+	assert.Equals(t, 0, len(spec.Peers))
+}
+
+func TestInterpolation(t *testing.T) {
+	type InterpolatedSpec struct {
+		Home    string `default:"${CONFIRE_TEST_HOME}/.myapp"`
+		Host    string `default:"localhost"`
+		Port    int    `default:"${CONFIRE_TEST_PORT:-8080}"`
+		Escaped string `default:"$${CONFIRE_TEST_HOME}"`
+		Nested  struct {
+			URL string `default:"http://${Host}:${Port}"`
+		}
+	}
+
+	t.Setenv("CONFIRE_TEST_HOME", "/home/confire")
+
+	spec := &InterpolatedSpec{}
+	err := defaults.Process(spec, defaults.WithInterpolation())
+	assert.Ok(t, err)
+
+	assert.Equals(t, "/home/confire/.myapp", spec.Home)
+	assert.Equals(t, 8080, spec.Port)
+	assert.Equals(t, "${CONFIRE_TEST_HOME}", spec.Escaped)
+	assert.Equals(t, "http://localhost:8080", spec.Nested.URL)
+
+	// Without the option, values are not interpolated
+	spec.Nested.URL = ""
+	err = defaults.Process(&spec.Nested)
+	assert.Ok(t, err)
+	assert.Equals(t, "http://${Host}:${Port}", spec.Nested.URL)
+
+	t.Run("Cycle", func(t *testing.T) {
+		spec := &struct {
+			A string `default:"${B}"`
+			B string `default:"${A}"`
+		}{}
+
+		err := defaults.Process(spec, defaults.WithInterpolation())
+		assert.ErrorIs(t, err, cerrors.ErrReferenceCycle)
+		assert.Equals(t, "confire: could not interpolate A from default: reference cycle A -> B -> A", err.Error())
+	})
+}
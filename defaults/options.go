@@ -0,0 +1,44 @@
+package defaults
+
+type Option func(opts *options) error
+
+// WithInterpolation expands ${VAR} references to environment variables and to other
+// fields of the spec in default values before they are parsed, e.g.
+// default:"${HOME}/.myapp". See the interpolate package for the supported syntax.
+func WithInterpolation() Option {
+	return func(opts *options) error {
+		opts.interpolate = true
+		return nil
+	}
+}
+
+// WithLookuper specifies how environment variables that are referenced by interpolated
+// default values are looked up rather than looking them up in the process environment.
+func WithLookuper(lookuper Lookuper) Option {
+	return func(opts *options) error {
+		opts.lookuper = lookuper
+		return nil
+	}
+}
+
+// Lookuper looks up the values of environment variables and is satisfied by all of the
+// lookupers in the env package (which cannot be imported by this package since the env
+// package applies defaults to the elements of slices of structs that it creates).
+type Lookuper interface {
+	Lookup(key string) (string, bool)
+}
+
+type options struct {
+	interpolate bool
+	lookuper    Lookuper
+}
+
+func makeOptions(opts ...Option) (conf *options, err error) {
+	conf = &options{}
+	for _, opt := range opts {
+		if err = opt(conf); err != nil {
+			return nil, err
+		}
+	}
+	return conf, nil
+}
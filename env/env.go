@@ -7,17 +7,21 @@ environment with required or default values.
 package env
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	goerrs "errors"
 
+	"go.rtnl.ai/confire/defaults"
 	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/interpolate"
 	"go.rtnl.ai/confire/parse"
+	"go.rtnl.ai/confire/source"
 	"go.rtnl.ai/confire/structs"
 )
 
@@ -26,56 +30,343 @@ const (
 	tagSplitWords = "split_words"
 	tagEnvConfig  = "envconfig"
 	tagEnv        = "env"
+	tagFile       = "file"
+	fileSuffix    = "_FILE"
 )
 
-// Process populates the specified struct based on environment variables.
-func Process(prefix string, spec interface{}) error {
-	infos, err := Gather(prefix, spec)
+// Process populates the specified struct based on environment variables. If a field
+// can be read from a file (see WithFiles) and its environment variable is not set, the
+// value is read from the file specified by the <KEY>_FILE environment variable.
+func Process(prefix string, spec interface{}, opts ...Option) error {
+	return process(prefix, spec, nil, opts...)
+}
+
+// Process the environment, recording the origin of each value that is set with the
+// gathered state (if any).
+func process(prefix string, spec interface{}, gathered *source.Gathered, opts ...Option) error {
+	opt, err := makeOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	// Gather the infos, growing slices and maps of structs to fit the variables that are
+	// set; the entries of maps of structs are stored in the map once they are processed.
+	var infos []Info
+	g := &gatherer{opt: opt}
+	defer g.commit()
+
+	if infos, err = g.gather(prefix, "", spec, false); err != nil {
+		return err
+	}
 
+	if opt.strict {
+		if err = opt.unknown(prefix, infos); err != nil {
+			return err
+		}
+	}
+
+	// Lookup all of the values before parsing so that interpolated values can refer to
+	// the values of other fields that are set from the environment.
+	values := make([]value, 0, len(infos))
 	for _, info := range infos {
 		// Try to find the environment variable
-		value, ok := os.LookupEnv(info.Key)
-		if !ok && info.Alt != "" {
-			value, ok = os.LookupEnv(info.Alt)
+		var ok bool
+		val := value{info: info}
+		if val.key, val.raw, ok, err = opt.lookup(info); err != nil {
+			return err
 		}
 
 		// If we didn't find an environment variable, skip the field
-		if !ok {
-			continue
+		if ok {
+			values = append(values, val)
 		}
+	}
+
+	var interp *interpolate.Interpolator
+	if opt.interpolate {
+		if interp, err = interpolate.New(spec, opt.lookuper.Lookup); err != nil {
+			return err
+		}
+
+		// The current values of fields may have been interpolated from secret fields
+		if gathered != nil {
+			for path, origin := range gathered.Origins {
+				if origin.Secret {
+					interp.SetSecret(path)
+				}
+			}
+		}
+
+		for _, val := range values {
+			interp.Set(val.info.Path, val.raw)
+		}
+	}
+
+	var errs errors.ParseErrors
+	for _, val := range values {
+		raw := val.raw
+		if interp != nil {
+			if raw, err = interp.Field(val.info.Path); err != nil {
+				return &errors.InterpolationError{Source: val.key, Field: val.info.Name, Err: err}
+			}
+		}
+
+		// Values interpolated from secret fields are as secret as the fields themselves
+		secret := interp != nil && interp.Secret(val.info.Path)
 
 		// Process the field from the environment
-		if err = parse.ParseField(value, info.Field); err != nil {
+		if err = parse.ParseField(raw, val.info.Field); err != nil {
+			if secret {
+				err = parse.MaskError(err)
+			}
+
 			target := &errors.ParseError{}
-			if goerrs.As(err, &target) {
-				target.Source = info.Key
+			if !goerrs.As(err, &target) {
+				return err
+			}
+
+			target.Source = val.key
+			if !opt.allErrors {
 				return target
 			}
-			return err
+
+			errs = append(errs, target)
+			continue
 		}
+
+		origin := source.Origin{Kind: source.Env, Location: val.key, Value: parse.RedactValue(val.info.Field, raw)}
+		if secret && raw != "" {
+			origin.Value, origin.Secret = parse.Mask, true
+		}
+		gathered.Set(val.info.Path, origin)
+	}
+
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return errs
 	}
+}
 
-	return err
+// value is the raw value of a field that was found in the environment.
+type value struct {
+	info Info
+	key  string
+	raw  string
 }
 
 // MustProcess is the same as Process but panics if an error occurs
-func MustProcess(prefix string, spec interface{}) {
-	if err := Process(prefix, spec); err != nil {
+func MustProcess(prefix string, spec interface{}, opts ...Option) {
+	if err := Process(prefix, spec, opts...); err != nil {
 		panic(err)
 	}
 }
 
+// Lookup the environment variable for the field, first by key and then by the alternate
+// key. If neither are set, the _FILE variables are looked up if enabled for the field.
+// Returns the source of the value for parse errors and origins.
+func (o *options) lookup(info Info) (key, value string, ok bool, err error) {
+	keys := []string{info.Key}
+	if info.Alt != "" {
+		keys = append(keys, info.Alt)
+	}
+
+	for _, key = range keys {
+		if value, ok = o.lookuper.Lookup(key); ok {
+			return key, value, true, nil
+		}
+	}
+
+	if !o.fileEnabled(info) {
+		return "", "", false, nil
+	}
+
+	for _, key = range keys {
+		key += fileSuffix
+
+		var path string
+		if path, ok = o.lookuper.Lookup(key); ok {
+			if value, err = o.readFile(key, path); err != nil {
+				return "", "", false, err
+			}
+			return fmt.Sprintf("%s (%s)", key, path), value, true, nil
+		}
+	}
+	return "", "", false, nil
+}
+
+// Returns the number of elements of the slice of structs with the specified key that
+// are configured by the environment, e.g. 3 if PREFIX_PEERS_2_HOST is set, so that the
+// slice can be grown to the highest index found. If the variables can be listed then
+// there may be gaps in the indices, otherwise the indices are looked up in order until
+// an element without any variables set is found. Only variables that end with the
+// variable of a field of the struct are counted so that a typo such as
+// PREFIX_PEERS_5_HSOT does not add elements to the slice.
+func (o *options) count(key string, elem reflect.Type) (n int) {
+	prefix := key + "_"
+	if lister, ok := o.lookuper.(KeyLister); ok {
+		suffixes, err := o.suffixes(elem)
+		if err != nil {
+			return 0
+		}
+
+		for _, key := range lister.Keys() {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+
+			index, rest, ok := strings.Cut(key[len(prefix):], "_")
+			if !ok || strings.Trim(index, "0123456789") != "" || !suffixes["_"+rest] {
+				continue
+			}
+
+			if i, err := strconv.Atoi(index); err == nil && i >= n {
+				n = i + 1
+			}
+		}
+		return n
+	}
+
+	for ; ; n++ {
+		infos, err := (&gatherer{}).gather(fmt.Sprintf("%s%d", prefix, n), "", newElement(elem), true)
+		if err != nil || !o.isSet(infos) {
+			return n
+		}
+	}
+}
+
+// Returns the segments of the variables that configure the entries of the map of
+// structs with the specified key, e.g. PRIMARY if PREFIX_DATABASES_PRIMARY_HOST is set,
+// by matching the end of each variable with the variable of a field of the struct. If
+// the variables cannot be listed then no segments are returned.
+func (o *options) segments(key string, elem reflect.Type) (segments []string) {
+	lister, ok := o.lookuper.(KeyLister)
+	if !ok {
+		return nil
+	}
+
+	suffixes, err := o.suffixes(elem)
+	if err != nil {
+		return nil
+	}
+
+	prefix := key + "_"
+	seen := make(map[string]struct{})
+	for _, key := range lister.Keys() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		// Prefer the longest field variable if more than one matches
+		rest, match := key[len(prefix):], ""
+		for suffix := range suffixes {
+			if len(suffix) > len(match) && len(rest) > len(suffix) && strings.HasSuffix(rest, suffix) {
+				match = suffix
+			}
+		}
+
+		if match == "" {
+			continue
+		}
+
+		segment := rest[:len(rest)-len(match)]
+		if _, ok := seen[segment]; !ok {
+			seen[segment] = struct{}{}
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// Returns the set of variables (and _FILE variables) of the fields of the struct type,
+// each with a leading underscore, e.g. _HOST, to match the end of the variables of the
+// elements of a slice or map of structs.
+func (o *options) suffixes(elem reflect.Type) (map[string]bool, error) {
+	infos, err := (&gatherer{}).gather("", "", newElement(elem), true)
+	if err != nil {
+		return nil, err
+	}
+
+	suffixes := make(map[string]bool, 2*len(infos))
+	for _, info := range infos {
+		suffixes["_"+info.Key] = true
+		if o.fileEnabled(info) {
+			suffixes["_"+info.Key+fileSuffix] = true
+		}
+	}
+	return suffixes, nil
+}
+
+// Returns true if any of the variables (or _FILE variables) of the fields are set.
+func (o *options) isSet(infos []Info) bool {
+	for _, info := range infos {
+		if _, ok := o.lookuper.Lookup(info.Key); ok {
+			return true
+		}
+
+		if o.fileEnabled(info) {
+			if _, ok := o.lookuper.Lookup(info.Key + fileSuffix); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Source loads environment variables as a configuration source using the prefix of
+// the gathered state to determine the environment variables to look up.
+type Source struct {
+	Options []Option // Any options for processing the environment
+}
+
+var _ source.Source = Source{}
+
+// Load implements the source.Source interface by processing the environment.
+func (s Source) Load(_ context.Context, spec interface{}, gathered *source.Gathered) error {
+	return process(gathered.Prefix, spec, gathered, s.Options...)
+}
+
 type Info struct {
 	Name  string         // Name of the field to compute the envvar from
 	Alt   string         // String specified by the env tag
 	Key   string         // The final environment variable key determined by the algorithm
+	Path  string         // The dotted path of the field in the spec (e.g. Database.URL)
 	Field *structs.Field // The actual field to set the envvar from (along with tags)
 }
 
 var gatherRegexp = regexp.MustCompile("([^A-Z]+|[A-Z]+[^A-Z]+|[A-Z]+)")
 var acronymRegexp = regexp.MustCompile("([A-Z]+)([A-Z][^A-Z]+)")
 
+// Gather the info for each of the fields in the spec that can be set from the environment.
+// Slices of structs are configured by indexed variables, e.g. PREFIX_PEERS_0_HOST, and
+// the info for the fields of each of the current elements of the slice is gathered.
 func Gather(prefix string, spec interface{}) (infos []Info, err error) {
+	return (&gatherer{}).gather(prefix, "", spec, false)
+}
+
+// gatherer collects the info for the fields of a spec; if the options are specified then
+// slices and maps of structs are grown to fit the variables that are set. Since the
+// struct values of a map cannot be set in place, each entry is gathered from a copy
+// that must be committed to the map once its fields have been processed.
+type gatherer struct {
+	opt     *options
+	commits []func()
+}
+
+// Store all of the copied entries in their maps.
+func (g *gatherer) commit() {
+	for _, commit := range g.commits {
+		commit()
+	}
+	g.commits = nil
+}
+
+// Gather the fields of the spec; the fields of the elements of a slice do not have an
+// alternate key since every element would share the same key.
+func (g *gatherer) gather(prefix, path string, spec interface{}, element bool) (infos []Info, err error) {
 	var s *structs.Struct
 	if s, err = structs.New(spec); err != nil {
 		return nil, errors.ErrInvalidSpecification
@@ -93,10 +384,10 @@ func Gather(prefix string, spec interface{}) (infos []Info, err error) {
 		}
 
 		// Handle pointers if necessary
-		for field.Kind() == reflect.Ptr {
+		for field.Kind() == reflect.Ptr && !parse.Registered(field.Type()) {
 			if field.IsNil() {
-				if field.TypeKind() != reflect.Struct {
-					// nil pointer to a non-struct: leave it alone.
+				if field.TypeKind() != reflect.Struct || parse.IsDecodable(field) {
+					// nil pointer to a non-struct or to a decodable struct: leave it alone.
 					break
 				}
 
@@ -112,25 +403,21 @@ func Gather(prefix string, spec interface{}) (infos []Info, err error) {
 		info := Info{
 			Name:  field.Name(),
 			Alt:   strings.ToUpper(oneOf(field.Tag(tagEnv), field.Tag(tagEnvConfig))),
+			Path:  field.Name(),
 			Field: field,
 		}
 
+		if path != "" {
+			info.Path = path + "." + info.Path
+		}
+
 		// Default to the field name as the envvar name (will be upcased)
 		info.Key = info.Name
 
 		// Best effort to un-pick camel casing as separate words
 		if isTrue(field.Tag(tagSplitWords)) {
-			words := gatherRegexp.FindAllStringSubmatch(field.Name(), -1)
-			if len(words) > 0 {
-				var name []string
-				for _, words := range words {
-					if m := acronymRegexp.FindStringSubmatch(words[0]); len(m) == 3 {
-						name = append(name, m[1], m[2])
-					} else {
-						name = append(name, words[0])
-					}
-				}
-				info.Key = strings.Join(name, "_")
+			if words := SplitWords(field.Name()); len(words) > 0 {
+				info.Key = strings.Join(words, "_")
 			}
 		}
 
@@ -142,19 +429,46 @@ func Gather(prefix string, spec interface{}) (infos []Info, err error) {
 			info.Key = fmt.Sprintf("%s_%s", prefix, info.Key)
 		}
 
+		if element {
+			info.Alt = ""
+		}
+
 		info.Key = strings.ToUpper(info.Key)
+
+		// Slices of structs are configured by the indexed variables of each element
+		if parse.IsStructSlice(field) {
+			var elements []Info
+			if elements, err = g.elements(info); err != nil {
+				return nil, err
+			}
+
+			infos = append(infos, elements...)
+			continue
+		}
+
+		// Maps of structs are configured by the variables of each entry by key
+		if isStructMap(field) {
+			var entries []Info
+			if entries, err = g.entries(info); err != nil {
+				return nil, err
+			}
+
+			infos = append(infos, entries...)
+			continue
+		}
+
 		infos = append(infos, info)
 
 		if field.Kind() == reflect.Struct {
 			// honor Decode interfaces if present
 			if !parse.IsDecodable(field) {
-				innerPrefix := prefix
+				innerPrefix, innerPath := prefix, path
 				if !field.IsEmbedded() {
-					innerPrefix = info.Key
+					innerPrefix, innerPath = info.Key, info.Path
 				}
 
 				embeddedPtr := field.Pointer()
-				embeddedInfos, err := Gather(innerPrefix, embeddedPtr)
+				embeddedInfos, err := g.gather(innerPrefix, innerPath, embeddedPtr, element)
 				if err != nil {
 					return nil, err
 				}
@@ -169,6 +483,151 @@ func Gather(prefix string, spec interface{}) (infos []Info, err error) {
 	return infos, nil
 }
 
+// Gather the fields of each element of the slice of structs, growing the slice to fit
+// the indexed variables that are set and applying the defaults to any new elements.
+func (g *gatherer) elements(info Info) (infos []Info, err error) {
+	field := info.Field
+	if g.opt != nil {
+		current := field.Len()
+		if err = field.Grow(g.opt.count(info.Key, field.Type().Elem())); err != nil {
+			return nil, err
+		}
+
+		for i := current; i < field.Len(); i++ {
+			if err = defaults.Process(element(field.Index(i))); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for i := 0; i < field.Len(); i++ {
+		prefix := fmt.Sprintf("%s_%d", info.Key, i)
+		path := fmt.Sprintf("%s[%d]", info.Path, i)
+
+		var elements []Info
+		if elements, err = g.gather(prefix, path, element(field.Index(i)), true); err != nil {
+			return nil, err
+		}
+		infos = append(infos, elements...)
+	}
+	return infos, nil
+}
+
+// Gather the fields of each entry of the map of structs, adding an entry (with the
+// defaults applied) for each key segment of the variables that are set, e.g. the entry
+// "primary" for PREFIX_DATABASES_PRIMARY_HOST. Keys that match an existing entry of the
+// map case-insensitively refer to that entry, otherwise the key is lower case. If the
+// options are not specified then the pattern of the variables is gathered instead,
+// e.g. PREFIX_DATABASES_<KEY>_HOST, to document the variables in usage.
+func (g *gatherer) entries(info Info) (infos []Info, err error) {
+	field := info.Field
+	typ := field.Type()
+	if g.opt == nil {
+		return g.gather(info.Key+"_<KEY>", info.Path+"[<KEY>]", newElement(typ.Elem()), true)
+	}
+
+	// Map the key segments of the variables to the keys of the map
+	m := field.Reflect()
+	keys := make(map[string]string, m.Len())
+	for _, key := range m.MapKeys() {
+		keys[strings.ToUpper(key.String())] = key.String()
+	}
+
+	for _, segment := range g.opt.segments(info.Key, typ.Elem()) {
+		if _, ok := keys[segment]; !ok {
+			keys[segment] = strings.ToLower(segment)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	segments := make([]string, 0, len(keys))
+	for segment := range keys {
+		segments = append(segments, segment)
+	}
+	sort.Strings(segments)
+
+	if m.IsNil() {
+		m.Set(reflect.MakeMapWithSize(typ, len(keys)))
+	}
+
+	for _, segment := range segments {
+		key := reflect.ValueOf(keys[segment]).Convert(typ.Key())
+
+		// Create a new entry or copy the existing entry so that its fields can be set
+		entry := m.MapIndex(key)
+		pointer := typ.Elem().Kind() == reflect.Ptr
+		switch {
+		case !entry.IsValid() || (pointer && entry.IsNil()):
+			entry = reflect.New(derefType(typ.Elem()))
+			if err = defaults.Process(entry.Interface()); err != nil {
+				return nil, err
+			}
+		case !pointer:
+			copied := reflect.New(typ.Elem())
+			copied.Elem().Set(entry)
+			entry = copied
+		}
+
+		if pointer {
+			m.SetMapIndex(key, entry)
+		} else {
+			value := entry.Elem()
+			g.commits = append(g.commits, func() { m.SetMapIndex(key, value) })
+		}
+
+		var fields []Info
+		if fields, err = g.gather(info.Key+"_"+segment, fmt.Sprintf("%s[%s]", info.Path, keys[segment]), entry.Interface(), true); err != nil {
+			return nil, err
+		}
+		infos = append(infos, fields...)
+	}
+	return infos, nil
+}
+
+// Returns a pointer to a new struct for the element type of a slice or map of structs.
+func newElement(elem reflect.Type) interface{} {
+	return reflect.New(derefType(elem)).Interface()
+}
+
+func derefType(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Ptr {
+		return typ.Elem()
+	}
+	return typ
+}
+
+// Returns a pointer to the struct of an element of a slice of structs.
+func element(elem *structs.Field) interface{} {
+	if elem.Kind() == reflect.Ptr {
+		return elem.Value()
+	}
+	return elem.Pointer()
+}
+
+// Returns true if the field is a map of structs with string keys, which are configured
+// by keyed variables; the keys of other maps cannot be taken from variable names.
+func isStructMap(field *structs.Field) bool {
+	return parse.IsStructMap(field) && field.Type().Key().Kind() == reflect.String
+}
+
+// SplitWords makes a best effort to split a CamelCase name into its separate words
+// while preserving acronyms, e.g. TCPHosts is split into TCP and Hosts.
+func SplitWords(name string) []string {
+	words := gatherRegexp.FindAllStringSubmatch(name, -1)
+	split := make([]string, 0, len(words))
+	for _, word := range words {
+		if m := acronymRegexp.FindStringSubmatch(word[0]); len(m) == 3 {
+			split = append(split, m[1], m[2])
+		} else {
+			split = append(split, word[0])
+		}
+	}
+	return split
+}
+
 func isTrue(s string) bool {
 	b, _ := strconv.ParseBool(s)
 	return b
@@ -1,15 +1,20 @@
 package env_test
 
 import (
+	goerrs "errors"
 	"flag"
 	"fmt"
+	"net"
 	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"go.rtnl.ai/confire/assert"
 	. "go.rtnl.ai/confire/env"
+	"go.rtnl.ai/confire/errors"
 )
 
 const testPrefix = "confire"
@@ -232,6 +237,439 @@ func TestCustomPointerFields(t *testing.T) {
 	assert.Equals(t, `setterstruct{"inner"}`, s.Struct.Inner)
 }
 
+func TestProcessFiles(t *testing.T) {
+	type FileSpecification struct {
+		Password string `split_words:"true" file:"true"`
+		Token    string
+		Port     int    `file:"false"`
+		Host     string `env:"HOSTNAME"`
+		Workers  int
+	}
+
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		assert.Ok(t, os.WriteFile(path, []byte(data), 0600))
+		return path
+	}
+
+	t.Setenv("CONFIRE_PASSWORD_FILE", write("password", "supersecret\n"))
+	t.Setenv("CONFIRE_TOKEN_FILE", write("token", "tokensecret\r\n"))
+	t.Setenv("CONFIRE_PORT_FILE", write("port", "8888"))
+	t.Setenv("HOSTNAME_FILE", write("host", "localhost"))
+
+	t.Run("Tagged", func(t *testing.T) {
+		// Only fields with the file tag are read from files by default
+		var s FileSpecification
+		assert.Ok(t, Process(testPrefix, &s))
+		assert.Equals(t, "supersecret", s.Password)
+		assert.Equals(t, "", s.Token)
+		assert.Equals(t, 0, s.Port)
+	})
+
+	t.Run("Global", func(t *testing.T) {
+		var s FileSpecification
+		assert.Ok(t, Process(testPrefix, &s, WithFiles()))
+		assert.Equals(t, "supersecret", s.Password)
+		assert.Equals(t, "tokensecret", s.Token)
+		assert.Equals(t, 0, s.Port)
+		assert.Equals(t, "localhost", s.Host)
+	})
+
+	t.Run("Precedence", func(t *testing.T) {
+		// The environment variable takes precedence over the file
+		t.Setenv("CONFIRE_PASSWORD", "fromenv")
+
+		var s FileSpecification
+		assert.Ok(t, Process(testPrefix, &s))
+		assert.Equals(t, "fromenv", s.Password)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		var s FileSpecification
+		err := Process(testPrefix, &s, WithMaxFileSize(4))
+		assert.ErrorIs(t, err, errors.ErrFileTooLarge)
+
+		path := filepath.Join(dir, "password")
+		assert.Equals(t, "confire: could not read CONFIRE_PASSWORD_FILE from "+path+": file is too large: exceeds 4 bytes", err.Error())
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		t.Setenv("CONFIRE_PASSWORD_FILE", filepath.Join(dir, "missing"))
+
+		var s FileSpecification
+		err := Process(testPrefix, &s)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("ParseError", func(t *testing.T) {
+		// Parse errors name both the environment variable and the path
+		path := write("workers", "notanumber")
+		t.Setenv("CONFIRE_WORKERS_FILE", path)
+
+		var s FileSpecification
+		err := Process(testPrefix, &s, WithFiles(), WithMaxFileSize(0))
+		assert.NotOk(t, err)
+		assert.True(t, strings.HasPrefix(err.Error(), "confire: could not parse Workers from CONFIRE_WORKERS_FILE ("+path+"): "))
+	})
+}
+
+func TestProcessInterpolation(t *testing.T) {
+	type InterpolatedSpec struct {
+		DataDir string `split_words:"true"`
+		Host    string
+		URL     string
+		Port    int
+	}
+
+	t.Setenv("CONFIRE_DATA_DIR", "${XDG_DATA_HOME:-/var/lib}/myapp")
+	t.Setenv("CONFIRE_URL", "http://${Host}:${Port:-80}")
+	t.Setenv("CONFIRE_HOST", "example.com")
+	t.Setenv("XDG_DATA_HOME", "")
+
+	var s InterpolatedSpec
+	assert.Ok(t, Process(testPrefix, &s, WithInterpolation()))
+	assert.Equals(t, "/var/lib/myapp", s.DataDir)
+	assert.Equals(t, "http://example.com:80", s.URL)
+
+	// References to fields not in the environment use the current value
+	s = InterpolatedSpec{Port: 8080}
+	assert.Ok(t, Process(testPrefix, &s, WithInterpolation()))
+	assert.Equals(t, "http://example.com:8080", s.URL)
+
+	// Without the option, values are not interpolated
+	s = InterpolatedSpec{}
+	assert.Ok(t, Process(testPrefix, &s))
+	assert.Equals(t, "http://${Host}:${Port:-80}", s.URL)
+
+	t.Run("Required", func(t *testing.T) {
+		t.Setenv("CONFIRE_PORT", "${CONFIRE_TEST_PORT:?port must be set}")
+		t.Setenv("CONFIRE_URL", "")
+
+		var s InterpolatedSpec
+		err := Process(testPrefix, &s, WithInterpolation())
+		assert.ErrorIs(t, err, errors.ErrUnsetVariable)
+		assert.Equals(t, "confire: could not interpolate Port from CONFIRE_PORT: variable is unset or empty: CONFIRE_TEST_PORT: port must be set", err.Error())
+	})
+}
+
+func TestProcessAllErrors(t *testing.T) {
+	vars := MapLookuper{
+		"CONFIRE_PORT":    "notanumber",
+		"CONFIRE_DEBUG":   "maybe",
+		"CONFIRE_TIMEOUT": "forever",
+		"CONFIRE_USER":    "admin",
+	}
+
+	// By default processing stops at the first parse error
+	var s Specification
+	err := Process(testPrefix, &s, WithLookuper(vars))
+	perr := &errors.ParseError{}
+	assert.True(t, goerrs.As(err, &perr))
+	assert.Equals(t, "CONFIRE_DEBUG", perr.Source)
+
+	s = Specification{}
+	err = Process(testPrefix, &s, WithLookuper(vars), WithAllErrors())
+	assert.NotOk(t, err)
+
+	var errs errors.ParseErrors
+	assert.True(t, goerrs.As(err, &errs))
+	assert.Equals(t, 3, len(errs))
+
+	sources := make([]string, 0, len(errs))
+	for _, perr := range errs {
+		sources = append(sources, perr.Source)
+	}
+	assert.Equals(t, []string{"CONFIRE_DEBUG", "CONFIRE_PORT", "CONFIRE_TIMEOUT"}, sources)
+	assert.True(t, strings.HasPrefix(err.Error(), "3 parse errors occurred:\n    - confire: could not parse Debug from CONFIRE_DEBUG"))
+
+	// Valid fields are still set
+	assert.Equals(t, "admin", s.User)
+
+	// A single parse error is not aggregated
+	vars = MapLookuper{"CONFIRE_PORT": "notanumber"}
+	err = Process(testPrefix, &s, WithLookuper(vars), WithAllErrors())
+	assert.True(t, goerrs.As(err, &perr))
+	assert.False(t, goerrs.As(err, &errs))
+}
+
+type PeerConfig struct {
+	Host    string
+	Region  string `env:"PEER_REGION"`
+	Port    int    `default:"4000"`
+	Tags    []string
+	Backoff struct {
+		Max time.Duration `default:"30s"`
+	}
+}
+
+func TestProcessSlices(t *testing.T) {
+	type SliceSpec struct {
+		Name     string
+		Peers    []PeerConfig
+		Replicas []*PeerConfig
+	}
+
+	vars := MapLookuper{
+		"CONFIRE_PEERS_0_HOST":         "alpha",
+		"CONFIRE_PEERS_0_PORT":         "4001",
+		"CONFIRE_PEERS_2_HOST":         "charlie",
+		"CONFIRE_PEERS_2_TAGS":         "a,b",
+		"CONFIRE_PEERS_2_BACKOFF_MAX":  "1m",
+		"CONFIRE_REPLICAS_0_HOST":      "replica",
+		"CONFIRE_PEERS_NOTANINDEX_X":   "ignored",
+		"CONFIRE_PEERS_1_HOST_EXTRA_1": "ignored",
+		"CONFIRE_PEERS_5_HSOT":         "ignored",
+		"PEER_REGION":                  "ignored",
+	}
+
+	// The slices are grown to the highest index of a variable of a field of the struct
+	// (not of a typo) and new elements get their defaults
+	var s SliceSpec
+	assert.Ok(t, Process(testPrefix, &s, WithLookuper(vars)))
+	assert.Equals(t, 3, len(s.Peers))
+	assert.Equals(t, "alpha", s.Peers[0].Host)
+	assert.Equals(t, "", s.Peers[0].Region)
+	assert.Equals(t, 4001, s.Peers[0].Port)
+	assert.Equals(t, 30*time.Second, s.Peers[0].Backoff.Max)
+	assert.Equals(t, "", s.Peers[1].Host)
+	assert.Equals(t, 4000, s.Peers[1].Port)
+	assert.Equals(t, "charlie", s.Peers[2].Host)
+	assert.Equals(t, []string{"a", "b"}, s.Peers[2].Tags)
+	assert.Equals(t, time.Minute, s.Peers[2].Backoff.Max)
+
+	assert.Equals(t, 1, len(s.Replicas))
+	assert.Equals(t, &PeerConfig{Host: "replica", Port: 4000, Backoff: struct {
+		Max time.Duration `default:"30s"`
+	}{Max: 30 * time.Second}}, s.Replicas[0])
+
+	// Existing elements are kept and are not reset to their defaults
+	s = SliceSpec{Peers: []PeerConfig{{Host: "zulu", Port: 9000}, {}, {}, {Host: "delta"}}}
+	assert.Ok(t, Process(testPrefix, &s, WithLookuper(vars)))
+	assert.Equals(t, 4, len(s.Peers))
+	assert.Equals(t, "alpha", s.Peers[0].Host)
+	assert.Equals(t, 4001, s.Peers[0].Port)
+	assert.Equals(t, 0, s.Peers[1].Port)
+	assert.Equals(t, "delta", s.Peers[3].Host)
+
+	// Parse errors refer to the indexed variable
+	vars["CONFIRE_PEERS_1_PORT"] = "notanumber"
+	s = SliceSpec{}
+	err := Process(testPrefix, &s, WithLookuper(vars))
+	assert.NotOk(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "confire: could not parse Port from CONFIRE_PEERS_1_PORT"))
+
+	t.Run("Environment", func(t *testing.T) {
+		// Without listing the variables the indices are looked up in order
+		t.Setenv("CONFIRE_PEERS_0_HOST", "alpha")
+		t.Setenv("CONFIRE_PEERS_1_PORT", "4002")
+		t.Setenv("CONFIRE_PEERS_3_HOST", "delta")
+
+		var s SliceSpec
+		assert.Ok(t, Process(testPrefix, &s, WithLookuper(lookuper{})))
+		assert.Equals(t, 2, len(s.Peers))
+		assert.Equals(t, 4002, s.Peers[1].Port)
+	})
+}
+
+// lookuper cannot list the variables that are set.
+type lookuper struct{}
+
+func (lookuper) Lookup(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+func TestGatherSlices(t *testing.T) {
+	spec := &struct {
+		Peers []PeerConfig
+	}{Peers: make([]PeerConfig, 2)}
+
+	infos, err := Gather(testPrefix, spec)
+	assert.Ok(t, err)
+
+	keys := make(map[string]string, len(infos))
+	for _, info := range infos {
+		keys[info.Path] = info.Key
+		assert.Equals(t, "", info.Alt)
+	}
+
+	assert.Equals(t, map[string]string{
+		"Peers[0].Host":        "CONFIRE_PEERS_0_HOST",
+		"Peers[0].Region":      "CONFIRE_PEERS_0_PEER_REGION",
+		"Peers[0].Port":        "CONFIRE_PEERS_0_PORT",
+		"Peers[0].Tags":        "CONFIRE_PEERS_0_TAGS",
+		"Peers[0].Backoff.Max": "CONFIRE_PEERS_0_BACKOFF_MAX",
+		"Peers[1].Host":        "CONFIRE_PEERS_1_HOST",
+		"Peers[1].Region":      "CONFIRE_PEERS_1_PEER_REGION",
+		"Peers[1].Port":        "CONFIRE_PEERS_1_PORT",
+		"Peers[1].Tags":        "CONFIRE_PEERS_1_TAGS",
+		"Peers[1].Backoff.Max": "CONFIRE_PEERS_1_BACKOFF_MAX",
+	}, keys)
+}
+
+type DBConfig struct {
+	Host     string
+	ReadOnly bool   `split_words:"true"`
+	Port     int    `default:"5432"`
+	Name     string `env:"DB_NAME"`
+}
+
+func TestProcessMaps(t *testing.T) {
+	type MapSpec struct {
+		Databases map[string]DBConfig
+		Replicas  map[string]*DBConfig
+		Colors    map[string]string
+	}
+
+	vars := MapLookuper{
+		"CONFIRE_DATABASES_PRIMARY_HOST":           "db1",
+		"CONFIRE_DATABASES_PRIMARY_PORT":           "5433",
+		"CONFIRE_DATABASES_READ_REPLICA_HOST":      "db2",
+		"CONFIRE_DATABASES_READ_REPLICA_READ_ONLY": "true",
+		"CONFIRE_DATABASES_UNKNOWN":                "ignored",
+		"CONFIRE_REPLICAS_EAST_HOST":               "east",
+		"CONFIRE_COLORS":                           "red:ff0000",
+		"DB_NAME":                                  "ignored",
+	}
+
+	var s MapSpec
+	assert.Ok(t, Process(testPrefix, &s, WithLookuper(vars)))
+	assert.Equals(t, map[string]DBConfig{
+		"primary":      {Host: "db1", Port: 5433},
+		"read_replica": {Host: "db2", ReadOnly: true, Port: 5432},
+	}, s.Databases)
+	assert.Equals(t, map[string]*DBConfig{"east": {Host: "east", Port: 5432}}, s.Replicas)
+	assert.Equals(t, map[string]string{"red": "ff0000"}, s.Colors)
+
+	// Existing entries are matched case-insensitively and are not reset to defaults
+	s = MapSpec{Databases: map[string]DBConfig{"Primary": {Host: "localhost", Port: 6000, Name: "app"}}}
+	assert.Ok(t, Process(testPrefix, &s, WithLookuper(MapLookuper{"CONFIRE_DATABASES_PRIMARY_HOST": "db1"})))
+	assert.Equals(t, map[string]DBConfig{"Primary": {Host: "db1", Port: 6000, Name: "app"}}, s.Databases)
+
+	// Parse errors refer to the variable of the entry
+	vars["CONFIRE_DATABASES_PRIMARY_PORT"] = "notanumber"
+	err := Process(testPrefix, &MapSpec{}, WithLookuper(vars))
+	assert.NotOk(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "confire: could not parse Port from CONFIRE_DATABASES_PRIMARY_PORT"))
+}
+
+func TestGatherMaps(t *testing.T) {
+	spec := &struct {
+		Databases map[string]DBConfig
+	}{Databases: map[string]DBConfig{"primary": {}}}
+
+	// The pattern of the variables is gathered rather than the existing entries
+	infos, err := Gather(testPrefix, spec)
+	assert.Ok(t, err)
+
+	keys := make(map[string]string, len(infos))
+	for _, info := range infos {
+		keys[info.Path] = info.Key
+	}
+
+	assert.Equals(t, map[string]string{
+		"Databases[<KEY>].Host":     "CONFIRE_DATABASES_<KEY>_HOST",
+		"Databases[<KEY>].ReadOnly": "CONFIRE_DATABASES_<KEY>_READ_ONLY",
+		"Databases[<KEY>].Port":     "CONFIRE_DATABASES_<KEY>_PORT",
+		"Databases[<KEY>].Name":     "CONFIRE_DATABASES_<KEY>_DB_NAME",
+	}, keys)
+}
+
+func TestProcessElementErrors(t *testing.T) {
+	vars := MapLookuper{"CONFIRE_COLORCODES": "red:1,blue:abc"}
+
+	var s Specification
+	err := Process(testPrefix, &s, WithLookuper(vars))
+
+	target := &errors.ParseError{}
+	assert.True(t, goerrs.As(err, &target))
+	assert.Equals(t, "ColorCodes[blue]", target.Path)
+	assert.Equals(t, `confire: could not parse ColorCodes[blue] from CONFIRE_COLORCODES: converting "abc" to type int: strconv.ParseInt: parsing "abc": invalid syntax`, err.Error())
+	assert.Equals(t, map[string]int(nil), s.ColorCodes)
+}
+
+func TestProcessJSON(t *testing.T) {
+	type JSONSpec struct {
+		Database DBConfig            `encoding:"json"`
+		Peers    []PeerConfig        `encoding:"json"`
+		Routes   map[string][]string `encoding:"json"`
+		Labels   map[string]string
+	}
+
+	vars := MapLookuper{
+		"CONFIRE_DATABASE": `{"Host": "db1", "Port": 5433}`,
+		"CONFIRE_PEERS":    `[{"Host": "alpha"}, {"Host": "bravo"}]`,
+		"CONFIRE_ROUTES":   `{"/api": ["GET", "POST"]}`,
+		"CONFIRE_LABELS":   `{"env": "prod"}`,
+	}
+
+	// Fields decoded from JSON are set by a single variable
+	var s JSONSpec
+	infos, err := Gather(testPrefix, &s)
+	assert.Ok(t, err)
+	assert.Equals(t, 4, len(infos))
+
+	assert.Ok(t, Process(testPrefix, &s, WithLookuper(vars), WithStrict()))
+	assert.Equals(t, DBConfig{Host: "db1", Port: 5433}, s.Database)
+	assert.Equals(t, []PeerConfig{{Host: "alpha"}, {Host: "bravo"}}, s.Peers)
+	assert.Equals(t, map[string][]string{"/api": {"GET", "POST"}}, s.Routes)
+	assert.Equals(t, map[string]string{"env": "prod"}, s.Labels)
+}
+
+func TestProcessRegistered(t *testing.T) {
+	type RegisteredSpec struct {
+		Bind     net.IP
+		Networks []net.IPNet
+		Zone     *time.Location
+		Endpoint *url.URL
+	}
+
+	vars := MapLookuper{
+		"CONFIRE_BIND":     "10.0.0.1",
+		"CONFIRE_NETWORKS": "10.0.0.0/8,192.168.0.0/16",
+		"CONFIRE_ZONE":     "America/New_York",
+		"CONFIRE_ENDPOINT": "https://example.com/api",
+	}
+
+	// Fields with registered parsers are set by a single variable
+	var s RegisteredSpec
+	infos, err := Gather(testPrefix, &s)
+	assert.Ok(t, err)
+	assert.Equals(t, 4, len(infos))
+
+	assert.Ok(t, Process(testPrefix, &s, WithLookuper(vars), WithStrict()))
+	assert.Equals(t, "10.0.0.1", s.Bind.String())
+	assert.Equals(t, 2, len(s.Networks))
+	assert.Equals(t, "192.168.0.0/16", s.Networks[1].String())
+	assert.Equals(t, "America/New_York", s.Zone.String())
+	assert.Equals(t, "example.com", s.Endpoint.Host)
+
+	vars["CONFIRE_BIND"] = "10.0.0.256"
+	err = Process(testPrefix, &s, WithLookuper(vars))
+	assert.NotOk(t, err)
+
+	perr := &errors.ParseError{}
+	assert.True(t, goerrs.As(err, &perr))
+	assert.Equals(t, "CONFIRE_BIND", perr.Source)
+	assert.Equals(t, "IP address", perr.Type)
+}
+
+func TestGatherPath(t *testing.T) {
+	var s Specification
+	infos, err := Gather(testPrefix, &s)
+	assert.Ok(t, err)
+
+	paths := make(map[string]string, len(infos))
+	for _, info := range infos {
+		paths[info.Key] = info.Path
+	}
+
+	// Embedded fields use their promoted names and nested fields are dotted
+	assert.Equals(t, "Debug", paths["CONFIRE_DEBUG"])
+	assert.Equals(t, "EmbeddedPort", paths["CONFIRE_EMBEDDEDPORT"])
+	assert.Equals(t, "NestedSpecification.Property", paths["CONFIRE_OUTER_INNER"])
+}
+
 func BenchmarkGather(b *testing.B) {
 	b.Cleanup(cleanupEnv())
 	setEnv()
@@ -0,0 +1,196 @@
+package env
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Lookuper looks up the values of environment variables. Implementations other than
+// the OS environment allow specifications to be processed from isolated environments,
+// e.g. to process several specifications concurrently in tests.
+type Lookuper interface {
+	// Lookup returns the value of the variable and true if it is set.
+	Lookup(key string) (string, bool)
+}
+
+// KeyLister is implemented by lookupers that can list all of the variables that are
+// set so that unknown variables can be detected in strict mode.
+type KeyLister interface {
+	Keys() []string
+}
+
+// OSLookuper looks up environment variables from the environment of the process.
+type OSLookuper struct{}
+
+var (
+	_ Lookuper  = OSLookuper{}
+	_ KeyLister = OSLookuper{}
+)
+
+func (OSLookuper) Lookup(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+func (OSLookuper) Keys() []string {
+	environ := os.Environ()
+	keys := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		if key, _, _ := strings.Cut(kv, "="); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// MapLookuper looks up environment variables from a map of keys to values.
+type MapLookuper map[string]string
+
+var (
+	_ Lookuper  = MapLookuper{}
+	_ KeyLister = MapLookuper{}
+)
+
+func (m MapLookuper) Lookup(key string) (string, bool) {
+	val, ok := m[key]
+	return val, ok
+}
+
+func (m MapLookuper) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// ChainLookuper looks up environment variables from each lookuper in order, returning
+// the value from the first lookuper that has the variable set.
+type ChainLookuper []Lookuper
+
+var (
+	_ Lookuper  = ChainLookuper{}
+	_ KeyLister = ChainLookuper{}
+)
+
+func (c ChainLookuper) Lookup(key string) (string, bool) {
+	for _, l := range c {
+		if val, ok := l.Lookup(key); ok {
+			return val, true
+		}
+	}
+	return "", false
+}
+
+// Keys returns the unique keys of all of the lookupers in the chain that can list keys.
+func (c ChainLookuper) Keys() (keys []string) {
+	seen := make(map[string]struct{})
+	for _, l := range c {
+		if lister, ok := l.(KeyLister); ok {
+			for _, key := range lister.Keys() {
+				if _, ok := seen[key]; !ok {
+					seen[key] = struct{}{}
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// LoadDotEnv reads the environment variables defined in a .env file.
+func LoadDotEnv(path string) (_ MapLookuper, err error) {
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var vars MapLookuper
+	if vars, err = ParseDotEnv(f); err != nil {
+		return nil, fmt.Errorf("confire: could not parse %s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// ParseDotEnv parses environment variables in the .env format: one KEY=value pair per
+// line with optional export prefixes, comments, and single or double quoted values.
+// Double quoted values may span multiple lines and support escape sequences; single
+// quoted values are literal. Unquoted values are trimmed and end at an inline comment.
+func ParseDotEnv(r io.Reader) (_ MapLookuper, err error) {
+	vars := make(MapLookuper)
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		start := lineno
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if key = strings.TrimSpace(key); !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=value", start)
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`):
+			// Double quoted values can span multiple lines
+			end := closingQuote(value)
+			for end < 0 && scanner.Scan() {
+				lineno++
+				value += "\n" + scanner.Text()
+				end = closingQuote(value)
+			}
+
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", start)
+			}
+
+			if rest := strings.TrimSpace(value[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+				return nil, fmt.Errorf("line %d: unexpected characters after quoted value", start)
+			}
+
+			if value, err = strconv.Unquote(strings.ReplaceAll(value[:end+1], "\n", `\n`)); err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted value", start)
+			}
+		case strings.HasPrefix(value, "'"):
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", start)
+			}
+			value = value[1 : end+1]
+		default:
+			if idx := strings.Index(value, " #"); idx >= 0 {
+				value = strings.TrimSpace(value[:idx])
+			}
+		}
+
+		vars[key] = value
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// Returns the index of the quote that closes the double quoted value or -1.
+func closingQuote(value string) int {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,165 @@
+package env_test
+
+import (
+	goerrs "errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.rtnl.ai/confire/assert"
+	. "go.rtnl.ai/confire/env"
+	"go.rtnl.ai/confire/errors"
+)
+
+func TestLookupers(t *testing.T) {
+	t.Setenv("CONFIRE_LOOKUP_OS", "fromos")
+
+	val, ok := OSLookuper{}.Lookup("CONFIRE_LOOKUP_OS")
+	assert.True(t, ok)
+	assert.Equals(t, "fromos", val)
+
+	vars := MapLookuper{"CONFIRE_LOOKUP_MAP": "frommap", "CONFIRE_LOOKUP_OS": "shadowed", "EMPTY": ""}
+	val, ok = vars.Lookup("CONFIRE_LOOKUP_MAP")
+	assert.True(t, ok)
+	assert.Equals(t, "frommap", val)
+
+	_, ok = vars.Lookup("CONFIRE_LOOKUP_MISSING")
+	assert.False(t, ok)
+
+	chain := ChainLookuper{vars, OSLookuper{}}
+	val, ok = chain.Lookup("CONFIRE_LOOKUP_OS")
+	assert.True(t, ok)
+	assert.Equals(t, "shadowed", val)
+
+	// Empty values are set and stop the chain
+	val, ok = ChainLookuper{vars, MapLookuper{"EMPTY": "notempty"}}.Lookup("EMPTY")
+	assert.True(t, ok)
+	assert.Equals(t, "", val)
+
+	_, ok = chain.Lookup("CONFIRE_LOOKUP_MISSING")
+	assert.False(t, ok)
+}
+
+func TestParseDotEnv(t *testing.T) {
+	data := `# comment
+CONFIRE_DEBUG=true
+export CONFIRE_PORT=8888
+CONFIRE_NAME = spaced out   # inline comment
+CONFIRE_HASH=abc#123
+CONFIRE_EMPTY=
+CONFIRE_SINGLE='literal $HOME \n # not a comment'
+CONFIRE_DOUBLE="escaped \"quotes\"\tand tabs" # comment
+CONFIRE_MULTI="first line
+second line"
+
+CONFIRE_EQUALS=a=b=c
+`
+
+	vars, err := ParseDotEnv(strings.NewReader(data))
+	assert.Ok(t, err)
+
+	expected := MapLookuper{
+		"CONFIRE_DEBUG":  "true",
+		"CONFIRE_PORT":   "8888",
+		"CONFIRE_NAME":   "spaced out",
+		"CONFIRE_HASH":   "abc#123",
+		"CONFIRE_EMPTY":  "",
+		"CONFIRE_SINGLE": `literal $HOME \n # not a comment`,
+		"CONFIRE_DOUBLE": "escaped \"quotes\"\tand tabs",
+		"CONFIRE_MULTI":  "first line\nsecond line",
+		"CONFIRE_EQUALS": "a=b=c",
+	}
+	assert.Equals(t, expected, vars)
+
+	testCases := []struct {
+		data string
+		err  string
+	}{
+		{"CONFIRE_DEBUG", "line 1: expected KEY=value"},
+		{"\n=true", "line 2: expected KEY=value"},
+		{"CONFIRE DEBUG=true", "line 1: expected KEY=value"},
+		{"A=\"unterminated\nB=2", "line 1: unterminated quoted value"},
+		{"A='unterminated", "line 1: unterminated quoted value"},
+		{`A="quoted" extra`, "line 1: unexpected characters after quoted value"},
+		{`A="\q"`, "line 1: invalid quoted value"},
+	}
+
+	for _, tc := range testCases {
+		_, err := ParseDotEnv(strings.NewReader(tc.data))
+		assert.NotOk(t, err)
+		assert.Equals(t, tc.err, err.Error())
+	}
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+	assert.Ok(t, os.WriteFile(path, []byte("CONFIRE_PORT=8888\nCONFIRE_USER=werebear\n"), 0600))
+
+	vars, err := LoadDotEnv(path)
+	assert.Ok(t, err)
+	assert.Equals(t, MapLookuper{"CONFIRE_PORT": "8888", "CONFIRE_USER": "werebear"}, vars)
+
+	_, err = LoadDotEnv(filepath.Join(dir, "missing.env"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	bad := filepath.Join(dir, "bad.env")
+	assert.Ok(t, os.WriteFile(bad, []byte("CONFIRE_PORT\n"), 0600))
+	_, err = LoadDotEnv(bad)
+	assert.Equals(t, "confire: could not parse "+bad+": line 1: expected KEY=value", err.Error())
+}
+
+func TestProcessLookuper(t *testing.T) {
+	// Specifications can be processed concurrently from isolated environments
+	for _, port := range []string{"8000", "8001", "8002", "8003"} {
+		port := port
+		t.Run(port, func(t *testing.T) {
+			t.Parallel()
+
+			var s Specification
+			vars := MapLookuper{"CONFIRE_PORT": port, "SERVICE_HOST": "localhost"}
+			assert.Ok(t, Process(testPrefix, &s, WithLookuper(vars)))
+			assert.Equals(t, port, strconv.Itoa(s.Port))
+			assert.Equals(t, "localhost", s.NoPrefixWithAlt)
+			assert.Equals(t, "", s.User)
+		})
+	}
+}
+
+func TestProcessStrict(t *testing.T) {
+	vars := MapLookuper{
+		"CONFIRE_PORT":      "8888",
+		"CONFIRE_TIMOUT":    "5s",
+		"CONFIRE_USER_FILE": "/run/secrets/user",
+		"CONFIRE_CONFIG":    "config.yaml",
+		"CONFIRE_ZZZ":       "unknown",
+		"SERVICE_HOST":      "localhost",
+		"OTHER_TIMOUT":      "ignored",
+	}
+
+	var s Specification
+	err := Process(testPrefix, &s, WithLookuper(vars), WithStrict("CONFIRE_CONFIG"))
+	assert.ErrorIs(t, err, errors.ErrUnknownKey)
+
+	var unknown *errors.UnknownEnvError
+	assert.True(t, goerrs.As(err, &unknown))
+	assert.Equals(t, []errors.UnknownEnv{
+		{Key: "CONFIRE_TIMOUT", Suggestion: "CONFIRE_TIMEOUT"},
+		{Key: "CONFIRE_USER_FILE"},
+		{Key: "CONFIRE_ZZZ"},
+	}, unknown.Vars)
+
+	delete(vars, "CONFIRE_TIMOUT")
+	delete(vars, "CONFIRE_ZZZ")
+	delete(vars, "CONFIRE_USER_FILE")
+	assert.Ok(t, Process(testPrefix, &s, WithLookuper(vars), WithStrict("CONFIRE_CONFIG")))
+
+	// File variables are known if reading from files is enabled
+	user := filepath.Join(t.TempDir(), "user")
+	assert.Ok(t, os.WriteFile(user, []byte("admin"), 0600))
+	vars["CONFIRE_USER_FILE"] = user
+	assert.Ok(t, Process(testPrefix, &s, WithLookuper(vars), WithStrict("CONFIRE_CONFIG"), WithFiles()))
+	assert.Equals(t, "admin", s.User)
+}
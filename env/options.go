@@ -0,0 +1,136 @@
+package env
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.rtnl.ai/confire/errors"
+)
+
+// DefaultMaxFileSize is the maximum size of a file that is read from a _FILE variable.
+const DefaultMaxFileSize = 1 << 20
+
+type Option func(opts *options) error
+
+// WithFiles allows every field to be read from the file specified by the <KEY>_FILE
+// environment variable, e.g. if MYAPP_DB_PASSWORD_FILE=/run/secrets/db is set then the
+// password is read from /run/secrets/db. Without this option only fields that have the
+// file:"true" struct tag are read from files. Fields with file:"false" are never read
+// from files.
+func WithFiles() Option {
+	return func(opts *options) error {
+		opts.files = true
+		return nil
+	}
+}
+
+// WithMaxFileSize specifies the maximum size in bytes of a file read from a _FILE
+// variable (1MiB by default). If the size is zero or negative, the size is not limited.
+func WithMaxFileSize(size int64) Option {
+	return func(opts *options) error {
+		opts.maxFileSize = size
+		return nil
+	}
+}
+
+// WithInterpolation expands ${VAR} references to environment variables and to other
+// fields of the spec in the values of environment variables before they are parsed,
+// e.g. MYAPP_DATA_DIR=${XDG_DATA_HOME:-/var/lib}/myapp. See the interpolate package
+// for the supported syntax.
+func WithInterpolation() Option {
+	return func(opts *options) error {
+		opts.interpolate = true
+		return nil
+	}
+}
+
+// WithLookuper specifies how environment variables are looked up rather than looking
+// them up in the environment of the process, e.g. from a map or a .env file.
+func WithLookuper(lookuper Lookuper) Option {
+	return func(opts *options) error {
+		opts.lookuper = lookuper
+		return nil
+	}
+}
+
+// WithAllErrors parses every field rather than stopping at the first invalid value,
+// returning an errors.ParseErrors that contains every ParseError if more than one
+// variable could not be parsed.
+func WithAllErrors() Option {
+	return func(opts *options) error {
+		opts.allErrors = true
+		return nil
+	}
+}
+
+// WithStrict returns an error if there are any environment variables with the prefix
+// that do not refer to a field in the specification, e.g. because of a typo. The error
+// lists the unknown variables along with the closest known variable. Any additional
+// known variables with the prefix that are used elsewhere can be specified.
+func WithStrict(known ...string) Option {
+	return func(opts *options) error {
+		opts.strict = true
+		opts.known = append(opts.known, known...)
+		return nil
+	}
+}
+
+type options struct {
+	files       bool
+	maxFileSize int64
+	interpolate bool
+	strict      bool
+	allErrors   bool
+	known       []string
+	lookuper    Lookuper
+}
+
+func makeOptions(opts ...Option) (conf *options, err error) {
+	conf = &options{maxFileSize: DefaultMaxFileSize, lookuper: OSLookuper{}}
+	for _, opt := range opts {
+		if err = opt(conf); err != nil {
+			return nil, err
+		}
+	}
+	return conf, nil
+}
+
+// Returns true if the field can be read from the file specified by a _FILE variable.
+func (o *options) fileEnabled(info Info) bool {
+	if tag := info.Field.Tag(tagFile); tag != "" {
+		return isTrue(tag)
+	}
+	return o.files
+}
+
+// Read the value of a _FILE variable from the file at path, trimming the trailing new
+// line that is usually added to secret files.
+func (o *options) readFile(key, path string) (_ string, err error) {
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return "", &errors.EnvFileError{Key: key, Path: path, Err: err}
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if o.maxFileSize > 0 {
+		r = io.LimitReader(f, o.maxFileSize+1)
+	}
+
+	var data []byte
+	if data, err = io.ReadAll(r); err != nil {
+		return "", &errors.EnvFileError{Key: key, Path: path, Err: err}
+	}
+
+	if o.maxFileSize > 0 && int64(len(data)) > o.maxFileSize {
+		return "", &errors.EnvFileError{
+			Key:  key,
+			Path: path,
+			Err:  fmt.Errorf("%w: exceeds %d bytes", errors.ErrFileTooLarge, o.maxFileSize),
+		}
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
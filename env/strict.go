@@ -0,0 +1,97 @@
+package env
+
+import (
+	"sort"
+	"strings"
+
+	"go.rtnl.ai/confire/errors"
+)
+
+// Returns an error if the lookuper has variables with the prefix that do not refer to
+// any of the gathered fields or any of the known keys. If the prefix is empty or the
+// lookuper cannot list its keys then no variables are checked.
+func (o *options) unknown(prefix string, infos []Info) error {
+	lister, ok := o.lookuper.(KeyLister)
+	if prefix == "" || !ok {
+		return nil
+	}
+
+	known := make(map[string]struct{}, len(infos)+len(o.known))
+	for _, key := range o.known {
+		known[strings.ToUpper(key)] = struct{}{}
+	}
+
+	for _, info := range infos {
+		for _, key := range []string{info.Key, info.Alt} {
+			if key == "" {
+				continue
+			}
+
+			known[key] = struct{}{}
+			if o.fileEnabled(info) {
+				known[key+fileSuffix] = struct{}{}
+			}
+		}
+	}
+
+	prefix = strings.ToUpper(prefix) + "_"
+	var vars []errors.UnknownEnv
+	for _, key := range lister.Keys() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		if _, ok := known[key]; !ok {
+			vars = append(vars, errors.UnknownEnv{Key: key, Suggestion: suggest(key, len(prefix), known)})
+		}
+	}
+
+	if len(vars) == 0 {
+		return nil
+	}
+
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Key < vars[j].Key })
+	return &errors.UnknownEnvError{Vars: vars}
+}
+
+// Returns the known key that is closest to the unknown key by edit distance if it is
+// close enough to likely be a typo of the key; the further the distance allowed the
+// longer the name of the variable is (excluding the prefix, which always matches).
+func suggest(key string, prefix int, known map[string]struct{}) (suggestion string) {
+	best := (len(key)-prefix)/3 + 1
+	for candidate := range known {
+		if d := distance(key, candidate); d < best || (d == best && suggestion != "" && candidate < suggestion) {
+			best, suggestion = d, candidate
+		}
+	}
+	return suggestion
+}
+
+// Computes the Levenshtein distance between two strings.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = prev[j] + 1
+			if ins := curr[j-1] + 1; ins < curr[j] {
+				curr[j] = ins
+			}
+			if sub := prev[j-1] + cost; sub < curr[j] {
+				curr[j] = sub
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -21,6 +21,20 @@ func IsParseError(err error) bool {
 	return errors.As(err, &target)
 }
 
+// Extract all of the parse errors from an error if it contains any; if there is only a
+// single parse error then it is returned as the only element of the parse errors.
+func ParseErrors(err error) (confireErrors.ParseErrors, bool) {
+	target := confireErrors.ParseErrors{}
+	if ok := errors.As(err, &target); ok {
+		return target, true
+	}
+
+	if perr, ok := ParseError(err); ok {
+		return confireErrors.ParseErrors{perr}, true
+	}
+	return nil, false
+}
+
 // Extract validation errors from an error if it is one.
 func ValidationErrors(err error) (confireErrors.ValidationErrors, bool) {
 	target := confireErrors.ValidationErrors{}
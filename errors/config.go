@@ -46,6 +46,7 @@ type InvalidConfig struct {
 	conf  string
 	field string
 	issue string
+	key   string
 	err   error
 }
 
@@ -64,6 +65,38 @@ func (e *InvalidConfig) Field() string {
 	return e.field
 }
 
+// Conf returns the dotted path of the configuration that contains the field, which is
+// empty if the error does not name the configuration it belongs to.
+func (e *InvalidConfig) Conf() string {
+	return e.conf
+}
+
+// Key returns the environment variable that sets the field if it is known, e.g. so that
+// users can be told which variable to set to fix the configuration.
+func (e *InvalidConfig) Key() string {
+	return e.key
+}
+
+// Within returns a copy of the error for a field that is nested in the configuration at
+// the dotted path, e.g. the Port field within Database is reported as Database.Port.
+func (e *InvalidConfig) Within(conf string) *InvalidConfig {
+	out := *e
+	if conf != "" {
+		if out.conf != "" {
+			conf = conf + "." + out.conf
+		}
+		out.conf = conf
+	}
+	return &out
+}
+
+// WithKey returns a copy of the error with the environment variable that sets the field.
+func (e *InvalidConfig) WithKey(key string) *InvalidConfig {
+	out := *e
+	out.key = key
+	return &out
+}
+
 func (e *InvalidConfig) Is(target error) bool {
 	return errors.Is(e.err, target)
 }
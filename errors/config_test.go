@@ -85,3 +85,30 @@ func TestInvalidConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestInvalidConfigWithin(t *testing.T) {
+	err := Required("", "Port")
+	nested := err.Within("Database")
+	assert.Equals(t, "Database.Port", nested.Field())
+	assert.Equals(t, "invalid configuration: Database.Port is required but not set", nested.Error())
+	assert.True(t, errors.Is(nested, ErrMissingRequired))
+
+	// The original error is not modified
+	assert.Equals(t, "Port", err.Field())
+	assert.Equals(t, "", err.Conf())
+	assert.Equals(t, "Database", nested.Conf())
+
+	nested = Invalid("window", "length", "must be positive").Within("Schedule")
+	assert.Equals(t, "Schedule.window.length", nested.Field())
+	assert.Equals(t, "Port", err.Within("").Field())
+}
+
+func TestInvalidConfigWithKey(t *testing.T) {
+	err := Required("Database", "Port")
+	assert.Equals(t, "", err.Key())
+
+	keyed := err.WithKey("MYAPP_DATABASE_PORT")
+	assert.Equals(t, "MYAPP_DATABASE_PORT", keyed.Key())
+	assert.Equals(t, err.Error(), keyed.Error())
+	assert.Equals(t, "", err.Key())
+}
@@ -12,6 +12,16 @@ var (
 	ErrNotExported          = errors.New("field is not exported")
 	ErrNotSettable          = errors.New("field is not settable")
 	ErrMissingRequired      = errors.New("required field is zero valued")
+	ErrUnknownFormat        = errors.New("unknown configuration file format")
+	ErrUnknownKey           = errors.New("unknown configuration key")
+	ErrDuplicateFlag        = errors.New("flag is already defined")
+	ErrDuplicateValidator   = errors.New("validator is already registered")
+	ErrFileTooLarge         = errors.New("file is too large")
+	ErrInvalidInterpolation = errors.New("invalid variable interpolation")
+	ErrUnsetVariable        = errors.New("variable is unset or empty")
+	ErrReferenceCycle       = errors.New("reference cycle detected")
+	ErrEmptySeparator       = errors.New("separator cannot be empty")
+	ErrConflictingOptions   = errors.New("options configure the default sources")
 )
 
 type ValidationErrors []*InvalidConfig
@@ -0,0 +1,67 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnknownKeyError is returned when a configuration file contains a key that does not
+// refer to any field in the specification.
+type UnknownKeyError struct {
+	Path string
+	Line int
+	Key  string
+}
+
+func (e *UnknownKeyError) Error() string {
+	return fmt.Sprintf("confire: unknown key %q in %s:%d", e.Key, e.Path, e.Line)
+}
+
+func (e *UnknownKeyError) Is(target error) bool {
+	return target == ErrUnknownKey
+}
+
+// EnvFileError is returned when the file specified by a _FILE environment variable
+// cannot be read.
+type EnvFileError struct {
+	Key  string
+	Path string
+	Err  error
+}
+
+func (e *EnvFileError) Error() string {
+	return fmt.Sprintf("confire: could not read %s from %s: %s", e.Key, e.Path, e.Err)
+}
+
+func (e *EnvFileError) Unwrap() error {
+	return e.Err
+}
+
+// UnknownEnvError is returned in strict mode when there are environment variables with
+// the prefix of the specification that do not refer to any field in the specification.
+type UnknownEnvError struct {
+	Vars []UnknownEnv
+}
+
+// UnknownEnv is an environment variable that does not refer to any field along with
+// the closest known environment variable (if any) as a suggestion.
+type UnknownEnv struct {
+	Key        string
+	Suggestion string
+}
+
+func (e *UnknownEnvError) Error() string {
+	vars := make([]string, 0, len(e.Vars))
+	for _, v := range e.Vars {
+		if v.Suggestion != "" {
+			vars = append(vars, fmt.Sprintf("%s (did you mean %s?)", v.Key, v.Suggestion))
+		} else {
+			vars = append(vars, v.Key)
+		}
+	}
+	return fmt.Sprintf("confire: unknown environment variables: %s", strings.Join(vars, ", "))
+}
+
+func (e *UnknownEnvError) Is(target error) bool {
+	return target == ErrUnknownKey
+}
@@ -0,0 +1,28 @@
+package errors_test
+
+import (
+	"errors"
+	"testing"
+
+	"go.rtnl.ai/confire/assert"
+	. "go.rtnl.ai/confire/errors"
+)
+
+func TestUnknownKeyError(t *testing.T) {
+	err := &UnknownKeyError{Path: "config.yaml", Line: 12, Key: "database.hots"}
+	assert.Equals(t, "confire: unknown key \"database.hots\" in config.yaml:12", err.Error())
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+	assert.False(t, errors.Is(err, ErrUnknownFormat))
+}
+
+func TestEnvFileError(t *testing.T) {
+	err := &EnvFileError{Key: "MYAPP_PASSWORD_FILE", Path: "/run/secrets/db", Err: ErrFileTooLarge}
+	assert.Equals(t, "confire: could not read MYAPP_PASSWORD_FILE from /run/secrets/db: file is too large", err.Error())
+	assert.True(t, errors.Is(err, ErrFileTooLarge))
+}
+
+func TestUnknownEnvError(t *testing.T) {
+	err := &UnknownEnvError{Vars: []UnknownEnv{{Key: "MYAPP_TIMOUT", Suggestion: "MYAPP_TIMEOUT"}, {Key: "MYAPP_FOO"}}}
+	assert.Equals(t, "confire: unknown environment variables: MYAPP_TIMOUT (did you mean MYAPP_TIMEOUT?), MYAPP_FOO", err.Error())
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+}
@@ -0,0 +1,37 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InterpolationError is returned when the variables in the value of a field cannot be
+// expanded, e.g. because a required variable is unset or the references are cyclic.
+type InterpolationError struct {
+	Source string
+	Field  string
+	Err    error
+}
+
+func (e *InterpolationError) Error() string {
+	return fmt.Sprintf("confire: could not interpolate %s from %s: %s", e.Field, e.Source, e.Err)
+}
+
+func (e *InterpolationError) Unwrap() error {
+	return e.Err
+}
+
+// CycleError is returned when the fields of a specification refer to each other such
+// that their values cannot be expanded. Fields is the path of the cycle, starting and
+// ending with the same field.
+type CycleError struct {
+	Fields []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("reference cycle %s", strings.Join(e.Fields, " -> "))
+}
+
+func (e *CycleError) Is(target error) bool {
+	return target == ErrReferenceCycle
+}
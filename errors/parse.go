@@ -3,18 +3,25 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 type ParseError struct {
 	Source string
 	Field  string
+	Path   string // The field and the index or key of the element that could not be parsed, e.g. Colors[red]
 	Type   string
 	Value  string
 	Err    error
 }
 
 func (e *ParseError) Error() string {
-	return fmt.Sprintf("confire: could not parse %[2]s from %[1]s: converting %[4]q to type %[3]s: %[5]s", e.Source, e.Field, e.Type, e.Value, e.Err)
+	field := e.Field
+	if e.Path != "" {
+		field = e.Path
+	}
+	return fmt.Sprintf("confire: could not parse %[2]s from %[1]s: converting %[4]q to type %[3]s: %[5]s", e.Source, field, e.Type, e.Value, e.Err)
 }
 
 func (e *ParseError) Is(target error) bool {
@@ -24,3 +31,49 @@ func (e *ParseError) Is(target error) bool {
 func (e *ParseError) Unwrap() error {
 	return e.Err
 }
+
+// ParseErrors collects all of the parse errors that occurred while processing a spec
+// so that every invalid value can be fixed at once rather than one at a time. Use
+// errors.As to extract the first ParseError or to extract the ParseErrors.
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%d parse errors occurred:", len(e)))
+	for _, err := range e {
+		sb.WriteString(fmt.Sprintf("\n    - %s", err.Error()))
+	}
+	return sb.String()
+}
+
+func (e ParseErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// RedactedError wraps the underlying error of a secret field whose message may contain
+// the secret value so that the value is not leaked into logs. The underlying error is
+// still available to errors.Is and errors.As.
+type RedactedError struct {
+	Err error
+}
+
+func (e *RedactedError) Error() string {
+	// Number errors have a cause that does not include the value (e.g. invalid syntax)
+	var num *strconv.NumError
+	if errors.As(e.Err, &num) {
+		return num.Err.Error()
+	}
+	return "secret value redacted"
+}
+
+func (e *RedactedError) Unwrap() error {
+	return e.Err
+}
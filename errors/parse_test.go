@@ -2,6 +2,7 @@ package errors_test
 
 import (
 	"errors"
+	"strconv"
 	"testing"
 
 	"go.rtnl.ai/confire/assert"
@@ -22,3 +23,37 @@ func TestParseError(t *testing.T) {
 	assert.Equals(t, werr, err.Unwrap())
 	assert.Equals(t, "confire: could not parse field from source: converting \"value\" to type foo: something bad happened", err.Error())
 }
+
+func TestParseErrorPath(t *testing.T) {
+	err := &ParseError{Source: "MYAPP_COLORS", Field: "Colors", Path: "Colors[red]", Type: "int", Value: "abc", Err: strconv.ErrSyntax}
+	assert.Equals(t, "confire: could not parse Colors[red] from MYAPP_COLORS: converting \"abc\" to type int: invalid syntax", err.Error())
+}
+
+func TestParseErrors(t *testing.T) {
+	werr := errors.New("something bad happened")
+	errs := ParseErrors{
+		{Source: "A", Field: "a", Type: "int", Value: "1", Err: werr},
+		{Source: "B", Field: "b", Type: "bool", Value: "2", Err: strconv.ErrSyntax},
+	}
+
+	assert.Equals(t, "2 parse errors occurred:\n    - confire: could not parse a from A: converting \"1\" to type int: something bad happened\n    - confire: could not parse b from B: converting \"2\" to type bool: invalid syntax", errs.Error())
+	assert.Equals(t, errs[0].Error(), errs[:1].Error())
+	assert.ErrorIs(t, errs, werr)
+	assert.ErrorIs(t, errs, strconv.ErrSyntax)
+
+	target := &ParseError{}
+	assert.True(t, errors.As(errs, &target))
+	assert.Equals(t, "A", target.Source)
+}
+
+func TestRedactedError(t *testing.T) {
+	werr := errors.New("could not parse \"sk-supersecret\"")
+	err := &RedactedError{Err: werr}
+	assert.ErrorIs(t, err, werr)
+	assert.Equals(t, "secret value redacted", err.Error())
+
+	_, nerr := strconv.Atoi("sk-supersecret")
+	err = &RedactedError{Err: nerr}
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+	assert.Equals(t, "invalid syntax", err.Error())
+}
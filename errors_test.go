@@ -1,6 +1,7 @@
 package confire_test
 
 import (
+	"fmt"
 	"testing"
 
 	"go.rtnl.ai/confire"
@@ -34,6 +35,33 @@ func TestParserError(t *testing.T) {
 	}
 }
 
+func TestParseErrors(t *testing.T) {
+	perr := &errors.ParseError{Source: "a", Field: "b", Type: "c", Value: "d", Err: errors.ErrNotAStruct}
+	testCases := []struct {
+		err      error
+		expected errors.ParseErrors
+	}{
+		{errors.ErrInvalidSpecification, nil},
+		{errors.Required("", "foo"), nil},
+		{perr, errors.ParseErrors{perr}},
+		{errors.ParseErrors{perr, perr}, errors.ParseErrors{perr, perr}},
+		{fmt.Errorf("wrapped: %w", errors.ParseErrors{perr, perr}), errors.ParseErrors{perr, perr}},
+	}
+
+	for _, tc := range testCases {
+		errs, ok := confire.ParseErrors(tc.err)
+		assert.Equals(t, tc.expected != nil, ok)
+		assert.Equals(t, tc.expected, errs)
+		assert.Equals(t, ok, confire.IsParseError(tc.err))
+
+		if ok {
+			target, ok := confire.ParseError(tc.err)
+			assert.True(t, ok)
+			assert.Equals(t, perr, target)
+		}
+	}
+}
+
 func TestIsParserError(t *testing.T) {
 	testCases := []struct {
 		err    error
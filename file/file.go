@@ -0,0 +1,498 @@
+/*
+Package file loads configuration values from a configuration file into a user defined
+specification. Keys in the file are matched to the fields of the specification using
+the same field walking rules as the env package: nested structs map to nested mappings
+and embedded structs are inlined into their parent. Scalar values are converted using
+the parse package so that Decoder, Setter, and TextUnmarshaler types work the same way
+that they do for environment variables and defaults.
+*/
+package file
+
+import (
+	"context"
+	goerrs "errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.rtnl.ai/confire/defaults"
+	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/parse"
+	"go.rtnl.ai/confire/source"
+	"go.rtnl.ai/confire/structs"
+)
+
+const (
+	tagIgnored = "ignored"
+	tagConfig  = "config"
+)
+
+// Process populates the specified struct from the configuration file at path. The
+// format of the file is determined by its extension unless specified by an option. An
+// error is returned if the file contains keys that do not refer to a field in the spec.
+func Process(path string, spec interface{}, opts ...Option) error {
+	return process(path, spec, nil, opts...)
+}
+
+// Process the configuration file, recording the origin of each value that is set with
+// the gathered state (if any).
+func process(path string, spec interface{}, gathered *source.Gathered, opts ...Option) (err error) {
+	var opt *options
+	if opt, err = makeOptions(path, opts...); err != nil {
+		return err
+	}
+
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		return err
+	}
+
+	var root *node
+	if root, err = decoders[opt.format](data); err != nil {
+		var serr *syntaxError
+		if goerrs.As(err, &serr) {
+			serr.path = path
+		}
+		return err
+	}
+
+	g := &gatherer{path: path, format: opt.format}
+	var infos []Info
+	if infos, err = g.gather(root, "", "", spec); err != nil {
+		return err
+	}
+
+	if err = g.err(); err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		location := fmt.Sprintf("%s:%d", path, info.Line)
+		if err = g.load(info.node, info.Key, info.Field); err != nil {
+			target := &errors.ParseError{}
+			if goerrs.As(err, &target) {
+				target.Source = location
+				return target
+			}
+			return err
+		}
+
+		gathered.Set(info.Path, source.Origin{Kind: source.File, Location: location, Value: parse.RedactValue(info.Field, info.node.value)})
+	}
+
+	return g.err()
+}
+
+// MustProcess is the same as Process but panics if an error occurs
+func MustProcess(path string, spec interface{}, opts ...Option) {
+	if err := Process(path, spec, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// Source loads a configuration file as a configuration source. If the path is empty
+// then no file is loaded.
+type Source struct {
+	Path    string   // The path to the configuration file to load
+	Options []Option // Any options for processing the configuration file
+}
+
+var _ source.Source = Source{}
+
+// Load implements the source.Source interface by processing the configuration file.
+func (s Source) Load(_ context.Context, spec interface{}, gathered *source.Gathered) error {
+	if s.Path == "" {
+		return nil
+	}
+	return process(s.Path, spec, gathered, s.Options...)
+}
+
+type Info struct {
+	Name  string         // Name of the field that the key was matched to
+	Key   string         // The dotted path of the keys in the file that set the field
+	Path  string         // The dotted path of the field in the spec (e.g. Database.URL)
+	Line  int            // The line in the file where the value was found
+	Field *structs.Field // The actual field to set the value on (along with tags)
+	node  *node
+}
+
+// gatherer matches the keys of decoded nodes to the fields of a spec, keeping track of
+// any keys in the file that do not refer to a field.
+type gatherer struct {
+	path    string
+	format  Format
+	unknown []*errors.UnknownKeyError
+}
+
+// Match the keys in the mapping node to the fields of the spec, returning the fields
+// that have values in the file. Nested structs are recursively gathered from nested
+// mappings unless they are decodable in which case they are treated as scalars.
+func (g *gatherer) gather(n *node, prefix, path string, spec interface{}) (infos []Info, err error) {
+	used := make(map[*pair]struct{}, len(n.pairs))
+	if infos, err = g.fields(n, prefix, path, spec, used); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range n.pairs {
+		if _, ok := used[entry]; !ok {
+			g.unknown = append(g.unknown, &errors.UnknownKeyError{
+				Path: g.path,
+				Line: entry.line,
+				Key:  join(prefix, entry.key),
+			})
+		}
+	}
+
+	return infos, nil
+}
+
+func (g *gatherer) fields(n *node, prefix, path string, spec interface{}, used map[*pair]struct{}) (infos []Info, err error) {
+	var s *structs.Struct
+	if s, err = structs.New(spec); err != nil {
+		return nil, errors.ErrInvalidSpecification
+	}
+
+	if !s.IsPointer() {
+		return nil, errors.ErrInvalidSpecification
+	}
+
+	for _, field := range s.Fields() {
+		// Skip any fields that cannot be set.
+		if !field.CanSet() {
+			continue
+		}
+
+		key := g.keyName(field)
+		if key == "-" {
+			continue
+		}
+
+		// Embedded structs without a key have their fields inlined into the parent.
+		if field.IsEmbedded() && key == "" && isNested(field) {
+			if isTrue(field.Tag(tagIgnored)) {
+				continue
+			}
+
+			if field.Kind() == reflect.Ptr && field.IsNil() {
+				if err = field.Init(); err != nil {
+					return nil, err
+				}
+			}
+
+			var embedded []Info
+			if embedded, err = g.fields(n, prefix, path, indirect(field).Pointer(), used); err != nil {
+				return nil, err
+			}
+			infos = append(infos, embedded...)
+			continue
+		}
+
+		if key == "" {
+			key = field.Name()
+		}
+
+		entry := lookup(n, key)
+		if entry == nil {
+			continue
+		}
+
+		// Ignored fields are known keys but are not loaded from the file.
+		used[entry] = struct{}{}
+		if isTrue(field.Tag(tagIgnored)) || entry.value.kind == nullNode {
+			continue
+		}
+
+		info := Info{
+			Name:  field.Name(),
+			Key:   join(prefix, entry.key),
+			Path:  join(path, field.Name()),
+			Line:  entry.line,
+			Field: field,
+			node:  entry.value,
+		}
+
+		// Recursively gather nested structs from nested mappings.
+		if entry.value.kind == mappingNode && isNested(field) {
+			if field.Kind() == reflect.Ptr && field.IsNil() {
+				if err = field.Init(); err != nil {
+					return nil, err
+				}
+			}
+
+			var nested []Info
+			if nested, err = g.gather(entry.value, info.Key, info.Path, indirect(field).Pointer()); err != nil {
+				return nil, err
+			}
+			infos = append(infos, nested...)
+			continue
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// Returns the key specified by the config or format struct tags (ignoring options).
+func (g *gatherer) keyName(field *structs.Field) string {
+	for _, tag := range []string{tagConfig, string(g.format)} {
+		if name, _, _ := strings.Cut(field.Tag(tag), ","); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// Load the value of the node into the field. Scalars are parsed directly from the
+// field so that the field name is reported in any parse errors.
+func (g *gatherer) load(n *node, key string, field *structs.Field) (err error) {
+	if n.kind == scalarNode && (parse.IsDecodable(field) || !hasStructs(field.Type())) {
+		return parse.ParseField(n.value, field)
+	}
+
+	if err = g.decode(n, key, field.Reflect()); err != nil {
+		target := &errors.ParseError{}
+		if goerrs.As(err, &target) {
+			if target.Field == "" {
+				target.Field = field.Name()
+			}
+			return parse.RedactError(field, target)
+		}
+
+		typ := field.Type()
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+
+		return parse.RedactError(field, &errors.ParseError{
+			Field: field.Name(),
+			Type:  typ.Name(),
+			Value: n.kind.String(),
+			Err:   err,
+		})
+	}
+	return nil
+}
+
+// Decode a node into a reflected value, recursively handling collections and structs.
+func (g *gatherer) decode(n *node, key string, v reflect.Value) (err error) {
+	switch n.kind {
+	case nullNode:
+		return nil
+	case scalarNode:
+		// Scalars are only parsed into structs that are decodable
+		if hasStructs(v.Type()) {
+			return structError(n, key, v.Type())
+		}
+		return parse.Parse(n.value, v)
+	}
+
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case n.kind == sequenceNode && v.Kind() == reflect.Slice:
+		sl := reflect.MakeSlice(v.Type(), len(n.items), len(n.items))
+		for i, item := range n.items {
+			if err = newElement(item, sl.Index(i)); err != nil {
+				return err
+			}
+
+			if err = g.decode(item, fmt.Sprintf("%s[%d]", key, i), sl.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(sl)
+	case n.kind == sequenceNode && v.Kind() == reflect.Array:
+		if len(n.items) > v.Len() {
+			return fmt.Errorf("cannot decode %d items into %s", len(n.items), v.Type())
+		}
+
+		for i, item := range n.items {
+			if err = g.decode(item, fmt.Sprintf("%s[%d]", key, i), v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case n.kind == mappingNode && v.Kind() == reflect.Map:
+		mp := reflect.MakeMapWithSize(v.Type(), len(n.pairs))
+		for _, entry := range n.pairs {
+			k := reflect.New(v.Type().Key()).Elem()
+			if err = parse.Parse(entry.key, k); err != nil {
+				return err
+			}
+
+			val := reflect.New(v.Type().Elem()).Elem()
+			if err = newElement(entry.value, val); err != nil {
+				return err
+			}
+
+			if err = g.decode(entry.value, join(key, entry.key), val); err != nil {
+				return err
+			}
+			mp.SetMapIndex(k, val)
+		}
+		v.Set(mp)
+	case n.kind == sequenceNode && v.Kind() == reflect.Struct:
+		return structError(n, key, v.Type())
+	case n.kind == mappingNode && v.Kind() == reflect.Struct && v.CanAddr():
+		var infos []Info
+		if infos, err = g.gather(n, key, "", v.Addr().Interface()); err != nil {
+			return err
+		}
+
+		for _, info := range infos {
+			if err = g.load(info.node, info.Key, info.Field); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot decode a %s into %s", n.kind, v.Type())
+	}
+	return nil
+}
+
+// Returns any unknown key errors found while gathering in the order of the file.
+func (g *gatherer) err() error {
+	sort.SliceStable(g.unknown, func(i, j int) bool { return g.unknown[i].Line < g.unknown[j].Line })
+
+	errs := make([]error, 0, len(g.unknown))
+	for _, err := range g.unknown {
+		errs = append(errs, err)
+	}
+	return goerrs.Join(errs...)
+}
+
+// Find the entry in the mapping for the specified key. Keys are matched without
+// regard to case, underscores, or dashes so that bind_addr, bind-addr, and bindAddr
+// all refer to the BindAddr field.
+func lookup(n *node, key string) *pair {
+	key = normalize(key)
+	for _, entry := range n.pairs {
+		if normalize(entry.key) == key {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Returns true if the field is a struct (or pointer to a struct) that is not decodable
+// and therefore should be populated from a nested mapping.
+func isNested(field *structs.Field) bool {
+	typ := field.Type()
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	return !parse.IsDecodableValue(reflect.New(typ).Elem())
+}
+
+// Initializes a new element of a slice or map that is decoded from a mapping with the
+// defaults of its struct (if it is a struct or a pointer to a struct that is not
+// decodable), as is done for the elements that are set by environment variables.
+func newElement(n *node, elem reflect.Value) error {
+	if n.kind != mappingNode || parse.IsDecodableValue(elem) {
+		return nil
+	}
+
+	typ := elem.Type()
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+
+	ptr := reflect.New(typ)
+	if err := defaults.Process(ptr.Interface()); err != nil {
+		return err
+	}
+
+	if elem.Kind() == reflect.Ptr {
+		elem.Set(ptr)
+	} else {
+		elem.Set(ptr.Elem())
+	}
+	return nil
+}
+
+// Returns true if the type is a struct that is not decodable (or a pointer, slice,
+// array, or map of such structs) and therefore can only be decoded from mappings.
+func hasStructs(typ reflect.Type) bool {
+	if parse.IsDecodableValue(reflect.New(typ).Elem()) {
+		return false
+	}
+
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return hasStructs(typ.Elem())
+	case reflect.Struct:
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns the error for a scalar or sequence that is specified for a struct, which must
+// be specified by a mapping, naming the key of the node in the file.
+func structError(n *node, key string, typ reflect.Type) error {
+	value := n.value
+	if n.kind != scalarNode {
+		value = n.kind.String()
+	}
+
+	return &errors.ParseError{
+		Path:  key,
+		Type:  typ.String(),
+		Value: value,
+		Err:   fmt.Errorf("cannot decode a %s into a struct", n.kind),
+	}
+}
+
+func indirect(field *structs.Field) *structs.Field {
+	for field.Kind() == reflect.Ptr && !field.IsNil() {
+		field = field.Elem()
+	}
+	return field
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func normalize(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
+
+func isTrue(s string) bool {
+	b, _ := strconv.ParseBool(s)
+	return b
+}
+
+// syntaxError is returned when a configuration file cannot be decoded.
+type syntaxError struct {
+	format string
+	path   string
+	line   int
+	msg    string
+}
+
+func (e *syntaxError) Error() string {
+	if e.path != "" {
+		return fmt.Sprintf("confire: could not decode %s file %s:%d: %s", e.format, e.path, e.line, e.msg)
+	}
+	return fmt.Sprintf("confire: could not decode %s: line %d: %s", e.format, e.line, e.msg)
+}
@@ -0,0 +1,269 @@
+package file_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/confire/assert"
+	confireErrors "go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/file"
+)
+
+type Specification struct {
+	Embedded
+	Debug        bool
+	Port         int
+	Rate         float32
+	BindAddr     string
+	Timeout      time.Duration
+	AdminUsers   []string
+	MagicNumbers []int
+	ColorCodes   map[string]int
+	Level        Level
+	Epoch        time.Time
+	Nothing      string `default:"something"`
+	Ignored      string `ignored:"true"`
+	Database     *Database
+	Peers        []Peer
+	Banner       string
+	Message      string `yaml:"motd,omitempty" toml:"motd" json:"motd"`
+	NotInFile    string
+}
+
+type Embedded struct {
+	Enabled bool
+}
+
+type Database struct {
+	URL      string
+	ReadOnly bool
+	Options  map[string]string
+}
+
+type Peer struct {
+	Name string
+	Port uint16
+}
+
+type Level uint8
+
+const (
+	LevelInfo Level = iota
+	LevelWarning
+)
+
+func (l *Level) Decode(value string) error {
+	switch strings.ToLower(value) {
+	case "info":
+		*l = LevelInfo
+	case "warning":
+		*l = LevelWarning
+	default:
+		return errors.New("unknown level")
+	}
+	return nil
+}
+
+func TestProcess(t *testing.T) {
+	for _, path := range []string{"testdata/config.yaml", "testdata/config.toml", "testdata/config.json"} {
+		t.Run(filepath.Ext(path), func(t *testing.T) {
+			var s Specification
+			err := file.Process(path, &s)
+			assert.Ok(t, err)
+			checkSpecification(t, s)
+		})
+	}
+}
+
+func TestProcessFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.conf")
+	data, err := os.ReadFile("testdata/config.toml")
+	assert.Ok(t, err)
+	assert.Ok(t, os.WriteFile(path, data, 0600))
+
+	var s Specification
+	err = file.Process(path, &s)
+	assert.ErrorIs(t, err, confireErrors.ErrUnknownFormat)
+
+	err = file.Process(path, &s, file.WithFormat("TOML"))
+	assert.Ok(t, err)
+	checkSpecification(t, s)
+
+	err = file.Process(path, &s, file.WithFormat("xml"))
+	assert.ErrorIs(t, err, confireErrors.ErrUnknownFormat)
+}
+
+func TestFormatOf(t *testing.T) {
+	testCases := map[string]file.Format{
+		"config.yaml":           file.YAML,
+		"/etc/myapp/config.YML": file.YAML,
+		"config.toml":           file.TOML,
+		"./config.json":         file.JSON,
+	}
+
+	for path, expected := range testCases {
+		format, err := file.FormatOf(path)
+		assert.Ok(t, err)
+		assert.Equals(t, expected, format)
+	}
+
+	_, err := file.FormatOf("config")
+	assert.ErrorIs(t, err, confireErrors.ErrUnknownFormat)
+}
+
+func TestUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	doc := "port: 8000\nprot: 8001\ndatabase:\n  url: sqlite://\n  hots: localhost\npeers:\n  - name: alpha\n    prot: 22\n"
+	assert.Ok(t, os.WriteFile(path, []byte(doc), 0600))
+
+	var s Specification
+	err := file.Process(path, &s)
+	assert.ErrorIs(t, err, confireErrors.ErrUnknownKey)
+
+	target := &confireErrors.UnknownKeyError{}
+	assert.True(t, errors.As(err, &target))
+	assert.Equals(t, path, target.Path)
+	assert.Equals(t, "prot", target.Key)
+	assert.Equals(t, 2, target.Line)
+
+	expected := fmt.Sprintf("confire: unknown key \"prot\" in %[1]s:2\nconfire: unknown key \"database.hots\" in %[1]s:5", path)
+	assert.Equals(t, expected, err.Error())
+
+	// Unknown keys in slices of structs are found when loading
+	doc = "peers:\n  - name: alpha\n    prot: 22\n"
+	assert.Ok(t, os.WriteFile(path, []byte(doc), 0600))
+
+	err = file.Process(path, &s)
+	assert.ErrorIs(t, err, confireErrors.ErrUnknownKey)
+	assert.Equals(t, fmt.Sprintf("confire: unknown key \"peers[0].prot\" in %s:3", path), err.Error())
+
+	// Keys for ignored fields are not unknown
+	doc = "ignored: foo\n"
+	assert.Ok(t, os.WriteFile(path, []byte(doc), 0600))
+	err = file.Process(path, &s)
+	assert.Ok(t, err)
+	assert.Equals(t, "", s.Ignored)
+}
+
+func checkSpecification(t *testing.T, s Specification) {
+	t.Helper()
+
+	assert.True(t, s.Enabled)
+	assert.True(t, s.Debug)
+	assert.Equals(t, 8888, s.Port)
+	assert.Equals(t, float32(0.25), s.Rate)
+	assert.Equals(t, "127.0.0.1:443", s.BindAddr)
+	assert.Equals(t, 5*time.Minute, s.Timeout)
+	assert.Equals(t, []string{"werewolf", "vampire", "ghast"}, s.AdminUsers)
+	assert.Equals(t, []int{3, 7, 12}, s.MagicNumbers)
+	assert.Equals(t, map[string]int{"red": 1, "green": 2, "blue": 3}, s.ColorCodes)
+	assert.Equals(t, LevelWarning, s.Level)
+	assert.Equals(t, time.Date(2023, 7, 19, 14, 53, 6, 0, time.UTC), s.Epoch)
+	assert.Equals(t, "", s.Nothing)
+	assert.Equals(t, "", s.Ignored)
+	assert.Equals(t, "", s.NotInFile)
+	assert.Equals(t, "Welcome to\nmy app!\n", s.Banner)
+	assert.Equals(t, "folded onto a single line", s.Message)
+
+	assert.Assert(t, s.Database != nil, "expected database to be allocated")
+	assert.Equals(t, "postgres://localhost:5432/myapp", s.Database.URL)
+	assert.Equals(t, map[string]string{"sslmode": "disable"}, s.Database.Options)
+	assert.Equals(t, []Peer{{"alpha", 1000}, {"bravo", 2000}}, s.Peers)
+}
+
+func TestProcessParseError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("debug: true\nport: notanumber\n"), 0600)
+	assert.Ok(t, err)
+
+	var s Specification
+	err = file.Process(path, &s)
+	assert.NotOk(t, err)
+
+	target := &confireErrors.ParseError{}
+	assert.True(t, errors.As(err, &target))
+	assert.Equals(t, path+":2", target.Source)
+	assert.Equals(t, "Port", target.Field)
+	assert.Equals(t, "notanumber", target.Value)
+}
+
+func TestProcessSyntaxError(t *testing.T) {
+	// Anchors and aliases are reported rather than loaded as part of the value
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("bind_addr: &addr 127.0.0.1:443\nhost: *addr\n"), 0600)
+	assert.Ok(t, err)
+
+	var s Specification
+	err = file.Process(path, &s)
+	assert.NotOk(t, err)
+	assert.Equals(t, fmt.Sprintf("confire: could not decode yaml file %s:1: anchors are not supported: &addr 127.0.0.1:443", path), err.Error())
+}
+
+func TestProcessElementDefaults(t *testing.T) {
+	type Node struct {
+		Host string
+		Port int `default:"7000"`
+	}
+
+	var s struct {
+		Peers    []Node
+		Pointers []*Node
+		Replicas map[string]Node
+		Names    []string
+	}
+
+	// New elements of slices and maps get the defaults of their struct
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	doc := "peers:\n  - host: alpha\n  - host: bravo\n    port: 7001\npointers:\n  - host: charlie\nreplicas:\n  east:\n    host: delta\nnames: [echo]\n"
+	assert.Ok(t, os.WriteFile(path, []byte(doc), 0600))
+
+	assert.Ok(t, file.Process(path, &s))
+	assert.Equals(t, []Node{{"alpha", 7000}, {"bravo", 7001}}, s.Peers)
+	assert.Equals(t, []*Node{{"charlie", 7000}}, s.Pointers)
+	assert.Equals(t, map[string]Node{"east": {"delta", 7000}}, s.Replicas)
+	assert.Equals(t, []string{"echo"}, s.Names)
+}
+
+func TestProcessStructErrors(t *testing.T) {
+	// Structs must be specified by mappings rather than being silently left unset
+	testCases := []struct {
+		doc string
+		err string
+	}{
+		{"database: 5\n", "could not parse database from %s:1: converting \"5\" to type *file_test.Database: cannot decode a scalar into a struct"},
+		{"peers: foo\n", "could not parse peers from %s:1: converting \"foo\" to type []file_test.Peer: cannot decode a scalar into a struct"},
+		{"database: [1, 2]\n", "could not parse database from %s:1: converting \"sequence\" to type file_test.Database: cannot decode a sequence into a struct"},
+		{"peers:\n  - name: alpha\n  - bravo\n", "could not parse peers[1] from %s:1: converting \"bravo\" to type file_test.Peer: cannot decode a scalar into a struct"},
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	for _, tc := range testCases {
+		assert.Ok(t, os.WriteFile(path, []byte(tc.doc), 0600))
+
+		var s Specification
+		err := file.Process(path, &s)
+		assert.NotOk(t, err)
+		assert.Equals(t, "confire: "+fmt.Sprintf(tc.err, path), err.Error())
+	}
+}
+
+func TestProcessErrors(t *testing.T) {
+	var s Specification
+	err := file.Process("testdata/doesnotexist.yaml", &s)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	err = file.Process("testdata/config.yaml", s)
+	assert.ErrorIs(t, err, confireErrors.ErrInvalidSpecification)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err = os.WriteFile(path, []byte("database: [1, 2]\n"), 0600)
+	assert.Ok(t, err)
+
+	err = file.Process(path, &s)
+	assert.NotOk(t, err)
+}
@@ -0,0 +1,110 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// decodeJSON parses a JSON document into nodes, tracking the line number of each value
+// so that errors can refer to the location in the file.
+func decodeJSON(data []byte) (_ *node, err error) {
+	p := &jsonParser{dec: json.NewDecoder(bytes.NewReader(data))}
+	p.dec.UseNumber()
+
+	// Compute the offset of the start of each line.
+	p.lines = []int{0}
+	for i, c := range data {
+		if c == '\n' {
+			p.lines = append(p.lines, i+1)
+		}
+	}
+
+	// An empty document is an empty object.
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &node{kind: mappingNode, line: 1}, nil
+	}
+
+	var root *node
+	if root, err = p.value(); err != nil {
+		return nil, err
+	}
+
+	if _, err = p.dec.Token(); err != io.EOF {
+		return nil, p.errorf("unexpected content after document")
+	}
+	return root, nil
+}
+
+type jsonParser struct {
+	dec   *json.Decoder
+	lines []int
+}
+
+func (p *jsonParser) value() (*node, error) {
+	tok, err := p.dec.Token()
+	if err != nil {
+		return nil, p.errorf("%s", err)
+	}
+
+	n := &node{line: p.line()}
+	switch v := tok.(type) {
+	case json.Delim:
+		switch v {
+		case '{':
+			n.kind = mappingNode
+			seen := make(map[string]struct{})
+			for p.dec.More() {
+				if tok, err = p.dec.Token(); err != nil {
+					return nil, p.errorf("%s", err)
+				}
+
+				entry := &pair{key: tok.(string), line: p.line()}
+				if _, ok := seen[entry.key]; ok {
+					return nil, p.errorf("duplicate key %q", entry.key)
+				}
+				seen[entry.key] = struct{}{}
+
+				if entry.value, err = p.value(); err != nil {
+					return nil, err
+				}
+				n.pairs = append(n.pairs, entry)
+			}
+		case '[':
+			n.kind = sequenceNode
+			for p.dec.More() {
+				var item *node
+				if item, err = p.value(); err != nil {
+					return nil, err
+				}
+				n.items = append(n.items, item)
+			}
+		}
+
+		// Consume the closing delimiter
+		if _, err = p.dec.Token(); err != nil {
+			return nil, p.errorf("%s", err)
+		}
+	case string:
+		n.kind, n.value = scalarNode, v
+	case json.Number:
+		n.kind, n.value = scalarNode, v.String()
+	case bool:
+		n.kind, n.value = scalarNode, fmt.Sprintf("%t", v)
+	case nil:
+		n.kind = nullNode
+	}
+	return n, nil
+}
+
+// Returns the line number of the most recently read token.
+func (p *jsonParser) line() int {
+	offset := int(p.dec.InputOffset())
+	return sort.Search(len(p.lines), func(i int) bool { return p.lines[i] >= offset })
+}
+
+func (p *jsonParser) errorf(format string, args ...interface{}) error {
+	return &syntaxError{format: "json", line: p.line(), msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,63 @@
+package file
+
+import (
+	"testing"
+
+	"go.rtnl.ai/confire/assert"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	doc := `{
+  "string": "foo",
+  "number": 3.14e10,
+  "bool": false,
+  "null": null,
+  "list": [1, "two", [3]],
+  "object": {
+    "nested": true
+  }
+}`
+
+	root, err := decodeJSON([]byte(doc))
+	assert.Ok(t, err)
+	assert.Equals(t, mappingNode, root.kind)
+	assert.Equals(t, 6, len(root.pairs))
+
+	assert.Equals(t, "foo", child(root, "string").value.value)
+	assert.Equals(t, 2, child(root, "string").line)
+	assert.Equals(t, "3.14e10", child(root, "number").value.value)
+	assert.Equals(t, "false", child(root, "bool").value.value)
+	assert.Equals(t, nullNode, child(root, "null").value.kind)
+
+	list := child(root, "list").value
+	assert.Equals(t, sequenceNode, list.kind)
+	assert.Equals(t, "two", list.items[1].value)
+	assert.Equals(t, "3", list.items[2].items[0].value)
+
+	object := child(root, "object").value
+	assert.Equals(t, mappingNode, object.kind)
+	assert.Equals(t, 8, child(object, "nested").line)
+
+	root, err = decodeJSON([]byte("  \n"))
+	assert.Ok(t, err)
+	assert.Equals(t, mappingNode, root.kind)
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	testCases := []struct {
+		doc string
+		err string
+	}{
+		{"{\"a\": 1,\n\"a\": 2}", "confire: could not decode json: line 2: duplicate key \"a\""},
+		{"{\"a\": 1}\n{}", "confire: could not decode json: line 2: unexpected content after document"},
+	}
+
+	for _, tc := range testCases {
+		_, err := decodeJSON([]byte(tc.doc))
+		assert.NotOk(t, err)
+		assert.Equals(t, tc.err, err.Error())
+	}
+
+	_, err := decodeJSON([]byte("{\"a\": }"))
+	assert.NotOk(t, err)
+}
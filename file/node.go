@@ -0,0 +1,44 @@
+package file
+
+// kind describes the type of a node that was decoded from a configuration file.
+type kind uint8
+
+const (
+	nullNode kind = iota
+	scalarNode
+	sequenceNode
+	mappingNode
+)
+
+// node is a format-agnostic representation of a decoded configuration file. Scalars
+// are kept as their raw strings so that they can be converted into the spec's types
+// using the same parsing mechanism as environment variables and defaults.
+type node struct {
+	kind  kind
+	line  int
+	value string
+	items []*node
+	pairs []*pair
+}
+
+// pair is a single key/value entry of a mapping node in the order it was decoded.
+type pair struct {
+	key   string
+	line  int
+	value *node
+}
+
+func (k kind) String() string {
+	switch k {
+	case nullNode:
+		return "null"
+	case scalarNode:
+		return "scalar"
+	case sequenceNode:
+		return "sequence"
+	case mappingNode:
+		return "mapping"
+	default:
+		return "unknown"
+	}
+}
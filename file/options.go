@@ -0,0 +1,74 @@
+package file
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"go.rtnl.ai/confire/errors"
+)
+
+type Option func(opts *options) error
+
+// WithFormat specifies the format of the configuration file rather than determining
+// the format from the file extension.
+func WithFormat(format Format) Option {
+	return func(opts *options) error {
+		format = Format(strings.ToLower(string(format)))
+		if _, ok := decoders[format]; !ok {
+			return fmt.Errorf("%w: %q", errors.ErrUnknownFormat, format)
+		}
+		opts.format = format
+		return nil
+	}
+}
+
+type options struct {
+	format Format
+}
+
+func makeOptions(path string, opts ...Option) (conf *options, err error) {
+	conf = &options{}
+	for _, opt := range opts {
+		if err = opt(conf); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.format == "" {
+		if conf.format, err = FormatOf(path); err != nil {
+			return nil, err
+		}
+	}
+	return conf, nil
+}
+
+// Format is the encoding of a configuration file.
+type Format string
+
+const (
+	YAML Format = "yaml"
+	TOML Format = "toml"
+	JSON Format = "json"
+)
+
+var decoders = map[Format]func([]byte) (*node, error){
+	YAML: decodeYAML,
+	TOML: decodeTOML,
+	JSON: decodeJSON,
+}
+
+var extensions = map[string]Format{
+	".yaml": YAML,
+	".yml":  YAML,
+	".toml": TOML,
+	".json": JSON,
+}
+
+// FormatOf returns the format of the configuration file from its extension.
+func FormatOf(path string) (Format, error) {
+	if format, ok := extensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return format, nil
+	}
+	return "", fmt.Errorf("%w: could not determine format of %s", errors.ErrUnknownFormat, path)
+}
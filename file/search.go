@@ -0,0 +1,55 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Extensions are the supported configuration file extensions in search order.
+var Extensions = []string{".yaml", ".yml", ".toml", ".json"}
+
+// SearchPaths returns the default directories that are searched for a configuration
+// file for the specified prefix: the current working directory, the prefix directory
+// in $XDG_CONFIG_HOME (or $HOME/.config if not set), and the prefix directory in /etc.
+// The lookup function is used to look up $XDG_CONFIG_HOME; if it is nil, then the
+// variable is looked up in the environment of the process.
+func SearchPaths(prefix string, lookup func(string) (string, bool)) []string {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	prefix = strings.ToLower(prefix)
+	paths := []string{"."}
+
+	if dir, _ := lookup("XDG_CONFIG_HOME"); dir != "" {
+		paths = append(paths, filepath.Join(dir, prefix))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".config", prefix))
+	}
+
+	return append(paths, filepath.Join("/etc", prefix))
+}
+
+// Find searches the directories in order for a configuration file with the specified
+// name and returns the path of the first file found. If the name does not have a
+// supported extension, then each supported extension is tried in turn.
+func Find(name string, dirs ...string) (path string, ok bool) {
+	names := []string{name}
+	if _, err := FormatOf(name); err != nil {
+		names = make([]string, 0, len(Extensions))
+		for _, ext := range Extensions {
+			names = append(names, name+ext)
+		}
+	}
+
+	for _, dir := range dirs {
+		for _, name := range names {
+			path = filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,57 @@
+package file_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.rtnl.ai/confire/assert"
+	"go.rtnl.ai/confire/file"
+)
+
+func TestSearchPaths(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/home/user/.config")
+	paths := file.SearchPaths("MyApp", nil)
+	assert.Equals(t, []string{".", "/home/user/.config/myapp", "/etc/myapp"}, paths)
+
+	// The lookup function is used rather than the environment if specified
+	lookup := func(key string) (string, bool) {
+		if key == "XDG_CONFIG_HOME" {
+			return "/srv/config", true
+		}
+		return "", false
+	}
+	paths = file.SearchPaths("myapp", lookup)
+	assert.Equals(t, []string{".", "/srv/config/myapp", "/etc/myapp"}, paths)
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/home/other")
+	paths = file.SearchPaths("myapp", nil)
+	assert.Equals(t, []string{".", "/home/other/.config/myapp", "/etc/myapp"}, paths)
+}
+
+func TestFind(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	assert.Ok(t, os.WriteFile(filepath.Join(second, "myapp.toml"), nil, 0600))
+	assert.Ok(t, os.WriteFile(filepath.Join(second, "myapp.json"), nil, 0600))
+	assert.Ok(t, os.Mkdir(filepath.Join(first, "myapp.yaml"), 0700))
+
+	path, ok := file.Find("myapp", first, second)
+	assert.True(t, ok)
+	assert.Equals(t, filepath.Join(second, "myapp.toml"), path)
+
+	path, ok = file.Find("myapp.json", first, second)
+	assert.True(t, ok)
+	assert.Equals(t, filepath.Join(second, "myapp.json"), path)
+
+	assert.Ok(t, os.WriteFile(filepath.Join(first, "myapp.yml"), nil, 0600))
+	path, ok = file.Find("myapp", first, second)
+	assert.True(t, ok)
+	assert.Equals(t, filepath.Join(first, "myapp.yml"), path)
+
+	_, ok = file.Find("other", first, second)
+	assert.False(t, ok)
+
+	_, ok = file.Find("myapp")
+	assert.False(t, ok)
+}
@@ -0,0 +1,440 @@
+package file
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// decodeTOML parses a TOML document into nodes. Tables, arrays of tables, dotted keys,
+// inline tables, arrays, and all string types are supported. Numbers, booleans, and
+// dates are kept as their raw strings (without digit separators) to be parsed into
+// the type of the field they are loaded into.
+func decodeTOML(data []byte) (_ *node, err error) {
+	p := &tomlParser{src: string(data), line: 1}
+	root := &node{kind: mappingNode, line: 1}
+	current := root
+
+	for {
+		p.skip(true)
+		if p.eof() {
+			return root, nil
+		}
+
+		line := p.line
+		switch {
+		case strings.HasPrefix(p.src[p.pos:], "[["):
+			p.pos += 2
+			var keys []string
+			if keys, err = p.keys(); err != nil {
+				return nil, err
+			}
+
+			if err = p.expect("]]"); err != nil {
+				return nil, err
+			}
+
+			if current, err = p.arrayTable(root, keys, line); err != nil {
+				return nil, err
+			}
+		case p.src[p.pos] == '[':
+			p.pos++
+			var keys []string
+			if keys, err = p.keys(); err != nil {
+				return nil, err
+			}
+
+			if err = p.expect("]"); err != nil {
+				return nil, err
+			}
+
+			if current, err = p.table(root, keys, line); err != nil {
+				return nil, err
+			}
+		default:
+			if err = p.keyValue(current); err != nil {
+				return nil, err
+			}
+		}
+
+		// Only a comment may follow a table header or key/value pair on a line.
+		p.skip(false)
+		if !p.eof() && p.src[p.pos] != '\n' && p.src[p.pos] != '\r' {
+			return nil, p.errorf("expected a new line after value")
+		}
+	}
+}
+
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+// Skip whitespace and comments, optionally skipping new lines as well.
+func (p *tomlParser) skip(newlines bool) {
+	for !p.eof() {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case c == '#':
+			for !p.eof() && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case newlines && (c == '\n' || c == '\r'):
+			if c == '\n' {
+				p.line++
+			}
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) expect(s string) error {
+	p.skip(false)
+	if !strings.HasPrefix(p.src[p.pos:], s) {
+		return p.errorf("expected %q", s)
+	}
+	p.pos += len(s)
+	return nil
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return &syntaxError{format: "toml", line: p.line, msg: fmt.Sprintf(format, args...)}
+}
+
+// Parse a dotted key made up of bare or quoted keys.
+func (p *tomlParser) keys() (keys []string, err error) {
+	for {
+		p.skip(false)
+		if p.eof() {
+			return nil, p.errorf("expected a key")
+		}
+
+		var key string
+		switch p.src[p.pos] {
+		case '"', '\'':
+			if key, err = p.str(); err != nil {
+				return nil, err
+			}
+		default:
+			start := p.pos
+			for !p.eof() && isBareKey(p.src[p.pos]) {
+				p.pos++
+			}
+
+			if key = p.src[start:p.pos]; key == "" {
+				return nil, p.errorf("invalid key")
+			}
+		}
+		keys = append(keys, key)
+
+		p.skip(false)
+		if p.eof() || p.src[p.pos] != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+// Parse a key/value pair and add it to the table.
+func (p *tomlParser) keyValue(table *node) (err error) {
+	line := p.line
+	var keys []string
+	if keys, err = p.keys(); err != nil {
+		return err
+	}
+
+	if err = p.expect("="); err != nil {
+		return err
+	}
+
+	var value *node
+	if value, err = p.value(); err != nil {
+		return err
+	}
+
+	// Create intermediate tables for dotted keys
+	for _, key := range keys[:len(keys)-1] {
+		if table, err = p.descend(table, key, line); err != nil {
+			return err
+		}
+	}
+
+	key := keys[len(keys)-1]
+	if child(table, key) != nil {
+		return p.errorf("duplicate key %q", key)
+	}
+
+	table.pairs = append(table.pairs, &pair{key: key, line: line, value: value})
+	return nil
+}
+
+// Returns the table for the key in the parent table, creating it if necessary. If the
+// key refers to an array of tables, the last table in the array is returned.
+func (p *tomlParser) descend(table *node, key string, line int) (*node, error) {
+	entry := child(table, key)
+	if entry == nil {
+		entry = &pair{key: key, line: line, value: &node{kind: mappingNode, line: line}}
+		table.pairs = append(table.pairs, entry)
+	}
+
+	switch {
+	case entry.value.kind == mappingNode:
+		return entry.value, nil
+	case entry.value.kind == sequenceNode && len(entry.value.items) > 0:
+		last := entry.value.items[len(entry.value.items)-1]
+		if last.kind == mappingNode {
+			return last, nil
+		}
+	}
+	return nil, p.errorf("key %q is not a table", key)
+}
+
+func (p *tomlParser) table(root *node, keys []string, line int) (table *node, err error) {
+	table = root
+	for _, key := range keys {
+		if table, err = p.descend(table, key, line); err != nil {
+			return nil, err
+		}
+	}
+	return table, nil
+}
+
+func (p *tomlParser) arrayTable(root *node, keys []string, line int) (table *node, err error) {
+	if table, err = p.table(root, keys[:len(keys)-1], line); err != nil {
+		return nil, err
+	}
+
+	key := keys[len(keys)-1]
+	entry := child(table, key)
+	if entry == nil {
+		entry = &pair{key: key, line: line, value: &node{kind: sequenceNode, line: line}}
+		table.pairs = append(table.pairs, entry)
+	}
+
+	if entry.value.kind != sequenceNode {
+		return nil, p.errorf("key %q is not an array of tables", key)
+	}
+
+	table = &node{kind: mappingNode, line: line}
+	entry.value.items = append(entry.value.items, table)
+	return table, nil
+}
+
+func (p *tomlParser) value() (_ *node, err error) {
+	p.skip(false)
+	if p.eof() {
+		return nil, p.errorf("expected a value")
+	}
+
+	n := &node{kind: scalarNode, line: p.line}
+	switch p.src[p.pos] {
+	case '"', '\'':
+		if n.value, err = p.str(); err != nil {
+			return nil, err
+		}
+	case '[':
+		n.kind = sequenceNode
+		p.pos++
+		for {
+			p.skip(true)
+			if p.eof() {
+				return nil, p.errorf("unterminated array")
+			}
+
+			if p.src[p.pos] == ']' {
+				p.pos++
+				return n, nil
+			}
+
+			var item *node
+			if item, err = p.value(); err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+
+			p.skip(true)
+			if !p.eof() && p.src[p.pos] == ',' {
+				p.pos++
+			} else if p.eof() || p.src[p.pos] != ']' {
+				return nil, p.errorf("expected ',' or ']' in array")
+			}
+		}
+	case '{':
+		n.kind = mappingNode
+		p.pos++
+		p.skip(false)
+		if !p.eof() && p.src[p.pos] == '}' {
+			p.pos++
+			return n, nil
+		}
+
+		for {
+			if err = p.keyValue(n); err != nil {
+				return nil, err
+			}
+
+			p.skip(false)
+			if p.eof() {
+				return nil, p.errorf("unterminated inline table")
+			}
+
+			switch p.src[p.pos] {
+			case ',':
+				p.pos++
+			case '}':
+				p.pos++
+				return n, nil
+			default:
+				return nil, p.errorf("expected ',' or '}' in inline table")
+			}
+		}
+	default:
+		if n.value, err = p.bare(); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+var (
+	tomlDate     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	tomlTime     = regexp.MustCompile(`^\d{2}:\d{2}(:\d{2})?`)
+	tomlDateTime = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}|\d{2}:\d{2})`)
+	tomlLiteral  = regexp.MustCompile(`^(true|false|[+-]?(inf|nan)|[+-]?[0-9][0-9a-fA-FxXoObB_.eE+-]*|\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}(:\d{2})?(\.\d+)?([Zz]|[+-]\d{2}:\d{2})?)?|\d{2}:\d{2}(:\d{2})?(\.\d+)?)$`)
+)
+
+// Parse a bare value such as a number, boolean, or date.
+func (p *tomlParser) bare() (string, error) {
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	value := p.src[start:p.pos]
+
+	// A date and time may be separated by a space rather than a T.
+	if tomlDate.MatchString(value) && p.pos+1 < len(p.src) && p.src[p.pos] == ' ' && tomlTime.MatchString(p.src[p.pos+1:]) {
+		p.pos++
+		start = p.pos
+		for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.src[p.pos])) {
+			p.pos++
+		}
+		value += "T" + p.src[start:p.pos]
+	}
+
+	if !tomlLiteral.MatchString(value) {
+		return "", p.errorf("invalid value %q", value)
+	}
+
+	// Remove digit separators from numbers but not from dates and times.
+	if !tomlDateTime.MatchString(value) {
+		value = strings.ReplaceAll(value, "_", "")
+	}
+	return value, nil
+}
+
+// Parse a basic, literal, or multi-line string.
+func (p *tomlParser) str() (string, error) {
+	quote := p.src[p.pos]
+	multi := strings.Repeat(string(quote), 3)
+
+	if strings.HasPrefix(p.src[p.pos:], multi) {
+		p.pos += 3
+		end := strings.Index(p.src[p.pos:], multi)
+		if end < 0 {
+			return "", p.errorf("unterminated multi-line string")
+		}
+
+		// Up to two additional quotes are allowed at the end of the string.
+		for p.pos+end+3 < len(p.src) && p.src[p.pos+end+3] == quote {
+			end++
+		}
+
+		raw := p.src[p.pos : p.pos+end]
+		p.line += strings.Count(raw, "\n")
+		p.pos += end + 3
+
+		// A newline immediately following the opening delimiter is trimmed.
+		raw = strings.TrimPrefix(strings.TrimPrefix(raw, "\r"), "\n")
+		if quote == '\'' {
+			return raw, nil
+		}
+		return p.unescape(raw, true)
+	}
+
+	p.pos++
+	start := p.pos
+	for ; !p.eof(); p.pos++ {
+		switch p.src[p.pos] {
+		case '\\':
+			if quote == '"' {
+				p.pos++
+			}
+		case '\n':
+			return "", p.errorf("unterminated string")
+		case quote:
+			raw := p.src[start:p.pos]
+			p.pos++
+			if quote == '\'' {
+				return raw, nil
+			}
+			return p.unescape(raw, false)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *tomlParser) unescape(s string, multiline bool) (string, error) {
+	var sb strings.Builder
+	for s != "" {
+		// A line ending backslash trims all whitespace up to the next character.
+		if multiline && s[0] == '\\' {
+			if rest := strings.TrimLeft(s[1:], " \t\r"); strings.HasPrefix(rest, "\n") {
+				s = strings.TrimLeft(rest, " \t\r\n")
+				continue
+			}
+		}
+
+		if s[0] == '"' {
+			sb.WriteByte('"')
+			s = s[1:]
+			continue
+		}
+
+		if strings.HasPrefix(s, `\e`) {
+			sb.WriteByte('\x1b')
+			s = s[2:]
+			continue
+		}
+
+		r, _, tail, err := strconv.UnquoteChar(s, '"')
+		if err != nil {
+			return "", p.errorf("invalid escape sequence in %q", s)
+		}
+		sb.WriteRune(r)
+		s = tail
+	}
+	return sb.String(), nil
+}
+
+// Returns the entry for the key in the table using an exact match.
+func child(table *node, key string) *pair {
+	for _, entry := range table.pairs {
+		if entry.key == key {
+			return entry
+		}
+	}
+	return nil
+}
+
+func isBareKey(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-'
+}
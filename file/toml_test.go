@@ -0,0 +1,124 @@
+package file
+
+import (
+	"testing"
+
+	"go.rtnl.ai/confire/assert"
+)
+
+func TestDecodeTOML(t *testing.T) {
+	doc := `
+# a comment
+title = "TOML \"test\"" # trailing comment
+literal = 'C:\Users\nodejs'
+multi = """
+Roses are red \
+  Violets are blue"""
+raw = '''
+first
+second'''
+integer = 1_000_000
+hex = 0xDEAD_BEEF
+negative = -17
+float = 6.626e-34
+infinity = -inf
+bool = false
+odt = 1979-05-27 07:32:00Z
+date = 1979-05-27
+time = 07:32:00
+site."google.com" = true
+inline = { first = "Tom", last.name = "Preston-Werner" }
+empty = {}
+nested = [[1, 2], ["a", 'b']]
+
+[servers.alpha]
+ip = "10.0.0.1"
+
+[servers.beta]
+ip = "10.0.0.2"
+
+[[products]]
+name = "Hammer"
+
+[[products]]
+
+[[products]]
+name = "Nail"
+colors = ["gray"]
+
+[products.dimensions]
+length = 2
+`
+
+	root, err := decodeTOML([]byte(doc))
+	assert.Ok(t, err)
+	assert.Equals(t, mappingNode, root.kind)
+
+	expected := map[string]string{
+		"title":    `TOML "test"`,
+		"literal":  `C:\Users\nodejs`,
+		"multi":    "Roses are red Violets are blue",
+		"raw":      "first\nsecond",
+		"integer":  "1000000",
+		"hex":      "0xDEADBEEF",
+		"negative": "-17",
+		"float":    "6.626e-34",
+		"infinity": "-inf",
+		"bool":     "false",
+		"odt":      "1979-05-27T07:32:00Z",
+		"date":     "1979-05-27",
+		"time":     "07:32:00",
+	}
+
+	for key, value := range expected {
+		entry := child(root, key)
+		assert.Assert(t, entry != nil, "expected key %q in document", key)
+		assert.Equals(t, scalarNode, entry.value.kind)
+		assert.Equals(t, value, entry.value.value)
+	}
+
+	assert.Equals(t, 3, child(root, "title").line)
+	assert.Equals(t, "true", child(child(root, "site").value, "google.com").value.value)
+
+	inline := child(root, "inline").value
+	assert.Equals(t, mappingNode, inline.kind)
+	assert.Equals(t, "Preston-Werner", child(child(inline, "last").value, "name").value.value)
+	assert.Equals(t, 0, len(child(root, "empty").value.pairs))
+
+	nested := child(root, "nested").value
+	assert.Equals(t, sequenceNode, nested.kind)
+	assert.Equals(t, "b", nested.items[1].items[1].value)
+
+	servers := child(root, "servers").value
+	assert.Equals(t, "10.0.0.2", child(child(servers, "beta").value, "ip").value.value)
+
+	products := child(root, "products").value
+	assert.Equals(t, sequenceNode, products.kind)
+	assert.Equals(t, 3, len(products.items))
+	assert.Equals(t, 0, len(products.items[1].pairs))
+	assert.Equals(t, "Nail", child(products.items[2], "name").value.value)
+	assert.Equals(t, "2", child(child(products.items[2], "dimensions").value, "length").value.value)
+}
+
+func TestDecodeTOMLErrors(t *testing.T) {
+	testCases := []struct {
+		doc string
+		err string
+	}{
+		{"a = 1\na = 2\n", "confire: could not decode toml: line 2: duplicate key \"a\""},
+		{"a = 1 b = 2\n", "confire: could not decode toml: line 1: expected a new line after value"},
+		{"a = bare\n", "confire: could not decode toml: line 1: invalid value \"bare\""},
+		{"a = \"unterminated\n", "confire: could not decode toml: line 1: unterminated string"},
+		{"a = [1, 2\n", "confire: could not decode toml: line 2: expected ',' or ']' in array"},
+		{"a = 1\n[a]\n", "confire: could not decode toml: line 2: key \"a\" is not a table"},
+		{"[a]\n[[a]]\n", "confire: could not decode toml: line 2: key \"a\" is not an array of tables"},
+		{"= 1\n", "confire: could not decode toml: line 1: invalid key"},
+		{"a\n", "confire: could not decode toml: line 1: expected \"=\""},
+	}
+
+	for _, tc := range testCases {
+		_, err := decodeTOML([]byte(tc.doc))
+		assert.NotOk(t, err)
+		assert.Equals(t, tc.err, err.Error())
+	}
+}
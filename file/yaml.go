@@ -0,0 +1,674 @@
+package file
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// decodeYAML parses the subset of YAML that is commonly used for configuration files:
+// block mappings and sequences, flow collections ([a, b] and {a: b}), plain, single
+// and double quoted scalars, literal (|) and folded (>) block scalars, and comments.
+// Anchors, aliases, tags, complex keys, and multiple documents are not supported.
+func decodeYAML(data []byte) (_ *node, err error) {
+	p := &yamlParser{}
+	if err = p.scan(string(data)); err != nil {
+		return nil, err
+	}
+
+	// An empty document is an empty mapping.
+	p.skip()
+	if p.eof() {
+		return &node{kind: mappingNode, line: 1}, nil
+	}
+
+	var root *node
+	if root, err = p.parseBlock(p.peek().indent); err != nil {
+		return nil, err
+	}
+
+	p.skip()
+	if !p.eof() {
+		return nil, p.errorf(p.peek().num, "unexpected content %q", p.peek().text)
+	}
+	return root, nil
+}
+
+// yamlLine is a single line of a YAML document with its indentation removed.
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// Split the document into lines, computing the indentation of each line and handling
+// document start and end markers.
+func (p *yamlParser) scan(data string) error {
+	content := false
+	for i, raw := range strings.Split(data, "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		text := strings.TrimLeft(raw, " ")
+		line := yamlLine{num: i + 1, indent: len(raw) - len(text), text: text}
+
+		if trimmed := strings.TrimSpace(text); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			if strings.HasPrefix(text, "\t") {
+				return p.errorf(line.num, "tabs are not allowed for indentation")
+			}
+
+			if line.indent == 0 {
+				if strings.HasPrefix(text, "%") && !content {
+					continue
+				}
+
+				if text == "---" || strings.HasPrefix(text, "--- ") {
+					if content {
+						return p.errorf(line.num, "multiple documents are not supported")
+					}
+					continue
+				}
+
+				if text == "..." {
+					break
+				}
+			}
+			content = true
+		}
+
+		p.lines = append(p.lines, line)
+	}
+	return nil
+}
+
+// Advance past any blank lines and comments.
+func (p *yamlParser) skip() {
+	for !p.eof() {
+		if text := strings.TrimSpace(p.lines[p.pos].text); text != "" && !strings.HasPrefix(text, "#") {
+			return
+		}
+		p.pos++
+	}
+}
+
+func (p *yamlParser) eof() bool {
+	return p.pos >= len(p.lines)
+}
+
+func (p *yamlParser) peek() yamlLine {
+	return p.lines[p.pos]
+}
+
+func (p *yamlParser) errorf(line int, format string, args ...interface{}) error {
+	return &syntaxError{format: "yaml", line: line, msg: fmt.Sprintf(format, args...)}
+}
+
+// Parse a block mapping or sequence whose entries are at the specified indentation.
+func (p *yamlParser) parseBlock(indent int) (*node, error) {
+	if isSequenceItem(p.peek().text) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseMapping(indent int) (_ *node, err error) {
+	n := &node{kind: mappingNode, line: p.peek().num}
+	seen := make(map[string]struct{})
+
+	for p.skip(); !p.eof(); p.skip() {
+		line := p.peek()
+		if line.indent < indent {
+			break
+		}
+
+		if line.indent > indent {
+			return nil, p.errorf(line.num, "unexpected indentation")
+		}
+
+		if isSequenceItem(line.text) {
+			return nil, p.errorf(line.num, "unexpected sequence item in mapping")
+		}
+
+		var key, rest string
+		if key, rest, err = splitKey(line.text); err != nil {
+			return nil, p.errorf(line.num, "%s", err)
+		}
+
+		if _, ok := seen[key]; ok {
+			return nil, p.errorf(line.num, "duplicate key %q", key)
+		}
+		seen[key] = struct{}{}
+
+		p.pos++
+		var value *node
+		if value, err = p.parseValue(line, indent, rest, true); err != nil {
+			return nil, err
+		}
+		n.pairs = append(n.pairs, &pair{key: key, line: line.num, value: value})
+	}
+
+	return n, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) (_ *node, err error) {
+	n := &node{kind: sequenceNode, line: p.peek().num}
+
+	for p.skip(); !p.eof(); p.skip() {
+		line := p.peek()
+		if line.indent < indent || (line.indent == indent && !isSequenceItem(line.text)) {
+			break
+		}
+
+		if line.indent > indent {
+			return nil, p.errorf(line.num, "unexpected indentation")
+		}
+
+		rest := strings.TrimPrefix(line.text, "-")
+		trimmed := strings.TrimLeft(rest, " ")
+		offset := indent + 1 + len(rest) - len(trimmed)
+
+		var item *node
+		if content := stripComment(trimmed); content != "" && (isSequenceItem(content) || isMappingEntry(content)) {
+			// Compact nested collections: treat the remainder of the line as if it
+			// were its own line at the deeper indentation.
+			p.lines[p.pos] = yamlLine{num: line.num, indent: offset, text: trimmed}
+			if item, err = p.parseBlock(offset); err != nil {
+				return nil, err
+			}
+		} else {
+			p.pos++
+			if item, err = p.parseValue(line, indent, trimmed, false); err != nil {
+				return nil, err
+			}
+		}
+		n.items = append(n.items, item)
+	}
+
+	return n, nil
+}
+
+// Parse the value that follows a mapping key or sequence indicator. If the value is
+// empty then the value is the nested block on the following lines (if any). Compact
+// sequences are sequences at the same indentation as their mapping key.
+func (p *yamlParser) parseValue(line yamlLine, indent int, rest string, compact bool) (*node, error) {
+	text := stripComment(rest)
+	switch {
+	case text == "":
+		p.skip()
+		if !p.eof() {
+			next := p.peek()
+			if next.indent > indent {
+				return p.parseBlock(next.indent)
+			}
+
+			if compact && next.indent == indent && isSequenceItem(next.text) {
+				return p.parseSequence(indent)
+			}
+		}
+		return &node{kind: nullNode, line: line.num}, nil
+	case text[0] == '|' || text[0] == '>':
+		return p.parseBlockScalar(line, indent, text)
+	case text[0] == '[' || text[0] == '{':
+		return p.parseFlow(line, text)
+	default:
+		value, err := parseYAMLScalar(text)
+		if err != nil {
+			return nil, p.errorf(line.num, "%s", err)
+		}
+		value.line = line.num
+		return value, nil
+	}
+}
+
+// Parse a literal or folded block scalar that is more indented than its parent.
+func (p *yamlParser) parseBlockScalar(line yamlLine, indent int, header string) (*node, error) {
+	var (
+		chomp    byte
+		explicit int
+	)
+
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomp = byte(c)
+		case c >= '1' && c <= '9':
+			explicit = int(c - '0')
+		default:
+			return nil, p.errorf(line.num, "invalid block scalar header %q", header)
+		}
+	}
+
+	blockIndent := -1
+	if explicit > 0 {
+		blockIndent = indent + explicit
+	}
+
+	var lines []string
+	for ; !p.eof(); p.pos++ {
+		l := p.peek()
+		if strings.TrimSpace(l.text) == "" {
+			lines = append(lines, "")
+			continue
+		}
+
+		if l.indent <= indent {
+			break
+		}
+
+		if blockIndent < 0 {
+			blockIndent = l.indent
+		}
+
+		if l.indent < blockIndent {
+			break
+		}
+		lines = append(lines, strings.Repeat(" ", l.indent-blockIndent)+l.text)
+	}
+
+	// Count the trailing blank lines for chomping.
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var sb strings.Builder
+	for i, l := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			switch {
+			case header[0] == '|':
+				sb.WriteByte('\n')
+			case l == "" || prev == "":
+				if l == "" {
+					sb.WriteByte('\n')
+				}
+			case strings.HasPrefix(l, " ") || strings.HasPrefix(prev, " "):
+				sb.WriteByte('\n')
+			default:
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(l)
+	}
+
+	value := sb.String()
+	if len(lines) > 0 {
+		switch chomp {
+		case '-':
+		case '+':
+			value += strings.Repeat("\n", trailing+1)
+		default:
+			value += "\n"
+		}
+	}
+
+	return &node{kind: scalarNode, line: line.num, value: value}, nil
+}
+
+// Parse a flow collection, which may span several lines until it is closed.
+func (p *yamlParser) parseFlow(line yamlLine, text string) (_ *node, err error) {
+	for depth := flowDepth(text); depth > 0; depth = flowDepth(text) {
+		p.skip()
+		if p.eof() {
+			return nil, p.errorf(line.num, "unterminated flow collection")
+		}
+		text += " " + stripComment(p.peek().text)
+		p.pos++
+	}
+
+	f := &flowParser{src: text, line: line.num}
+	var n *node
+	if n, err = f.parse(); err != nil {
+		return nil, p.errorf(line.num, "%s", err)
+	}
+	return n, nil
+}
+
+// flowParser parses YAML flow collections from a single logical line.
+type flowParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (f *flowParser) parse() (n *node, err error) {
+	if n, err = f.value(); err != nil {
+		return nil, err
+	}
+
+	f.space()
+	if f.pos < len(f.src) {
+		return nil, fmt.Errorf("unexpected %q after flow collection", f.src[f.pos:])
+	}
+	return n, nil
+}
+
+func (f *flowParser) value() (*node, error) {
+	f.space()
+	if f.pos >= len(f.src) {
+		return nil, fmt.Errorf("unexpected end of flow collection")
+	}
+
+	switch f.src[f.pos] {
+	case '[':
+		return f.sequence()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		s, err := f.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: scalarNode, line: f.line, value: s}, nil
+	default:
+		n, err := parseYAMLScalar(f.plain(",]}"))
+		if err != nil {
+			return nil, err
+		}
+		n.line = f.line
+		return n, nil
+	}
+}
+
+func (f *flowParser) sequence() (*node, error) {
+	n := &node{kind: sequenceNode, line: f.line}
+	f.pos++
+
+	for {
+		f.space()
+		if f.pos < len(f.src) && f.src[f.pos] == ']' {
+			f.pos++
+			return n, nil
+		}
+
+		item, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		n.items = append(n.items, item)
+
+		if err = f.separator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (f *flowParser) mapping() (_ *node, err error) {
+	n := &node{kind: mappingNode, line: f.line}
+	f.pos++
+
+	for {
+		f.space()
+		if f.pos < len(f.src) && f.src[f.pos] == '}' {
+			f.pos++
+			return n, nil
+		}
+
+		var key string
+		if f.pos < len(f.src) && (f.src[f.pos] == '"' || f.src[f.pos] == '\'') {
+			if key, err = f.quoted(); err != nil {
+				return nil, err
+			}
+			f.space()
+		} else {
+			key = f.plain(":,}")
+		}
+
+		if key == "" {
+			return nil, fmt.Errorf("missing key in flow mapping")
+		}
+
+		value := &node{kind: nullNode, line: f.line}
+		if f.pos < len(f.src) && f.src[f.pos] == ':' {
+			f.pos++
+			f.space()
+			if f.pos < len(f.src) && f.src[f.pos] != ',' && f.src[f.pos] != '}' {
+				if value, err = f.value(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		n.pairs = append(n.pairs, &pair{key: key, line: f.line, value: value})
+
+		if err = f.separator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Consume a comma between items or stop before the closing delimiter.
+func (f *flowParser) separator(end byte) error {
+	f.space()
+	if f.pos >= len(f.src) {
+		return fmt.Errorf("unterminated flow collection")
+	}
+
+	switch f.src[f.pos] {
+	case ',':
+		f.pos++
+		return nil
+	case end:
+		return nil
+	default:
+		return fmt.Errorf("expected ',' or %q in flow collection", end)
+	}
+}
+
+func (f *flowParser) quoted() (string, error) {
+	end := quoteEnd(f.src[f.pos:])
+	if end < 0 {
+		return "", fmt.Errorf("unterminated quoted string")
+	}
+
+	s, err := unquoteYAML(f.src[f.pos : f.pos+end+1])
+	if err != nil {
+		return "", err
+	}
+	f.pos += end + 1
+	return s, nil
+}
+
+func (f *flowParser) plain(stops string) string {
+	start := f.pos
+	for ; f.pos < len(f.src); f.pos++ {
+		if strings.IndexByte(stops, f.src[f.pos]) >= 0 {
+			break
+		}
+	}
+	return strings.TrimSpace(f.src[start:f.pos])
+}
+
+func (f *flowParser) space() {
+	for f.pos < len(f.src) && f.src[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+// Parse a single line scalar, returning a null node for YAML null values.
+func parseYAMLScalar(text string) (*node, error) {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return &node{kind: nullNode}, nil
+	}
+
+	if text[0] == '"' || text[0] == '\'' {
+		if end := quoteEnd(text); end != len(text)-1 {
+			return nil, fmt.Errorf("invalid quoted scalar %s", text)
+		}
+
+		value, err := unquoteYAML(text)
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: scalarNode, value: value}, nil
+	}
+
+	// Plain scalars cannot start with the anchor, alias, or tag indicators; rather than
+	// loading the indicator as part of the value, report that they are not supported.
+	switch text[0] {
+	case '&':
+		return nil, fmt.Errorf("anchors are not supported: %s", text)
+	case '*':
+		return nil, fmt.Errorf("aliases are not supported: %s", text)
+	case '!':
+		return nil, fmt.Errorf("tags are not supported: %s", text)
+	}
+
+	return &node{kind: scalarNode, value: text}, nil
+}
+
+// Split a mapping entry into its key and the (possibly empty) remainder of the line.
+func splitKey(text string) (key, rest string, err error) {
+	if text[0] == '"' || text[0] == '\'' {
+		end := quoteEnd(text)
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated quoted key")
+		}
+
+		if key, err = unquoteYAML(text[:end+1]); err != nil {
+			return "", "", err
+		}
+
+		rest = strings.TrimLeft(text[end+1:], " ")
+		if !strings.HasPrefix(rest, ":") || (len(rest) > 1 && rest[1] != ' ') {
+			return "", "", fmt.Errorf("expected ':' after key %q", key)
+		}
+		return key, strings.TrimLeft(rest[1:], " "), nil
+	}
+
+	if text[0] == '[' || text[0] == '{' || text[0] == '?' {
+		return "", "", fmt.Errorf("complex mapping keys are not supported")
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] == '#' && i > 0 && text[i-1] == ' ' {
+			break
+		}
+
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			if key = strings.TrimSpace(text[:i]); key == "" {
+				break
+			}
+			return key, strings.TrimLeft(text[i+1:], " "), nil
+		}
+	}
+
+	return "", "", fmt.Errorf("expected a mapping key in %q", text)
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func isMappingEntry(text string) bool {
+	if text[0] == '[' || text[0] == '{' {
+		return false
+	}
+	_, _, err := splitKey(text)
+	return err == nil
+}
+
+// Remove a trailing comment from the text, ignoring # characters that are inside of
+// quoted strings or that are not preceded by whitespace.
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+				continue
+			}
+			quote = 0
+		case quote != 0:
+		case (c == '"' || c == '\'') && startsToken(text[:i]):
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return strings.TrimRight(text[:i], " \t")
+		}
+	}
+	return strings.TrimRight(text, " \t")
+}
+
+// Returns true if a quote following the prefix would start a quoted scalar.
+func startsToken(prefix string) bool {
+	prefix = strings.TrimRight(prefix, " ")
+	return prefix == "" || strings.ContainsAny(prefix[len(prefix)-1:], "[{,:-")
+}
+
+// Compute the number of unclosed flow collections in the text.
+func flowDepth(text string) (depth int) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+// Returns the index of the closing quote of the quoted string that starts the text or
+// -1 if the string is not terminated.
+func quoteEnd(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote:
+			if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+func unquoteYAML(text string) (string, error) {
+	if text[0] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+
+	// YAML double quoted escapes are mostly a superset of Go escapes.
+	var sb strings.Builder
+	for s := text[1 : len(text)-1]; s != ""; {
+		if len(s) > 1 && s[0] == '\\' {
+			if r, ok := yamlEscapes[s[1]]; ok {
+				sb.WriteString(r)
+				s = s[2:]
+				continue
+			}
+		}
+
+		r, _, tail, err := strconv.UnquoteChar(s, '"')
+		if err != nil {
+			return "", fmt.Errorf("invalid double quoted string %s", text)
+		}
+		sb.WriteRune(r)
+		s = tail
+	}
+	return sb.String(), nil
+}
+
+var yamlEscapes = map[byte]string{'/': "/", ' ': " ", 'e': "\x1b", '0': "\x00"}
@@ -0,0 +1,131 @@
+package file
+
+import (
+	"testing"
+
+	"go.rtnl.ai/confire/assert"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	doc := `
+%YAML 1.2
+---
+# a comment
+name: 'it''s a test'   # trailing comment
+quoted: "tab\there # not a comment"
+url: https://example.com/#anchor
+empty:
+nulls: null
+list:
+  - a
+  - "b, c"
+  -
+    - nested
+compact:
+- one
+- key: value
+  other: 42
+flow: [1, [2, 3], {a: b}]
+multiline: [
+  alpha,
+  bravo,
+]
+literal: |+
+  line one
+    indented
+
+folded: >
+  first
+  second
+
+  third
+"quoted key": value
+...
+ignored: after end
+`
+
+	root, err := decodeYAML([]byte(doc))
+	assert.Ok(t, err)
+	assert.Equals(t, mappingNode, root.kind)
+	assert.Equals(t, 12, len(root.pairs))
+
+	expected := map[string]string{
+		"name":       "it's a test",
+		"quoted":     "tab\there # not a comment",
+		"url":        "https://example.com/#anchor",
+		"literal":    "line one\n  indented\n\n",
+		"folded":     "first second\nthird\n",
+		"quoted key": "value",
+	}
+
+	for key, value := range expected {
+		entry := lookup(root, key)
+		assert.Assert(t, entry != nil, "expected key %q in document", key)
+		assert.Equals(t, scalarNode, entry.value.kind)
+		assert.Equals(t, value, entry.value.value)
+	}
+
+	assert.Equals(t, nullNode, lookup(root, "empty").value.kind)
+	assert.Equals(t, nullNode, lookup(root, "nulls").value.kind)
+	assert.Equals(t, 5, lookup(root, "name").line)
+
+	list := lookup(root, "list").value
+	assert.Equals(t, sequenceNode, list.kind)
+	assert.Equals(t, 3, len(list.items))
+	assert.Equals(t, "b, c", list.items[1].value)
+	assert.Equals(t, sequenceNode, list.items[2].kind)
+	assert.Equals(t, "nested", list.items[2].items[0].value)
+
+	compact := lookup(root, "compact").value
+	assert.Equals(t, sequenceNode, compact.kind)
+	assert.Equals(t, 2, len(compact.items))
+	assert.Equals(t, mappingNode, compact.items[1].kind)
+	assert.Equals(t, "42", lookup(compact.items[1], "other").value.value)
+
+	flow := lookup(root, "flow").value
+	assert.Equals(t, sequenceNode, flow.kind)
+	assert.Equals(t, 3, len(flow.items))
+	assert.Equals(t, "3", flow.items[1].items[1].value)
+	assert.Equals(t, "b", lookup(flow.items[2], "a").value.value)
+
+	multiline := lookup(root, "multiline").value
+	assert.Equals(t, 2, len(multiline.items))
+	assert.Equals(t, "bravo", multiline.items[1].value)
+}
+
+func TestDecodeYAMLEmpty(t *testing.T) {
+	for _, doc := range []string{"", "\n\n", "# just a comment\n", "---\n"} {
+		root, err := decodeYAML([]byte(doc))
+		assert.Ok(t, err)
+		assert.Equals(t, mappingNode, root.kind)
+		assert.Equals(t, 0, len(root.pairs))
+	}
+}
+
+func TestDecodeYAMLErrors(t *testing.T) {
+	testCases := []struct {
+		doc string
+		err string
+	}{
+		{"a: 1\n  b: 2\n", "confire: could not decode yaml: line 2: unexpected indentation"},
+		{"a: 1\na: 2\n", "confire: could not decode yaml: line 2: duplicate key \"a\""},
+		{"a: 1\n- b\n", "confire: could not decode yaml: line 2: unexpected sequence item in mapping"},
+		{"\ta: 1\n", "confire: could not decode yaml: line 1: tabs are not allowed for indentation"},
+		{"a: [1, 2\n", "confire: could not decode yaml: line 1: unterminated flow collection"},
+		{"a: \"unterminated\n", "confire: could not decode yaml: line 1: invalid quoted scalar \"unterminated"},
+		{"just a string\n", "confire: could not decode yaml: line 1: expected a mapping key in \"just a string\""},
+		{"a: 1\n---\nb: 2\n", "confire: could not decode yaml: line 2: multiple documents are not supported"},
+		{"a: &base foo\n", "confire: could not decode yaml: line 1: anchors are not supported: &base foo"},
+		{"a: foo\nb: *a\n", "confire: could not decode yaml: line 2: aliases are not supported: *a"},
+		{"a: !!str 42\n", "confire: could not decode yaml: line 1: tags are not supported: !!str 42"},
+		{"a:\n  - x\n  - *a\n", "confire: could not decode yaml: line 3: aliases are not supported: *a"},
+		{"a: [x, *a]\n", "confire: could not decode yaml: line 1: aliases are not supported: *a"},
+		{"a: &base\n  b: 1\n", "confire: could not decode yaml: line 1: anchors are not supported: &base"},
+	}
+
+	for _, tc := range testCases {
+		_, err := decodeYAML([]byte(tc.doc))
+		assert.NotOk(t, err)
+		assert.Equals(t, tc.err, err.Error())
+	}
+}
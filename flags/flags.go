@@ -0,0 +1,270 @@
+/*
+Package flags exposes the fields of a configuration specification as command line flags
+so that any configuration value can also be set from the command line. Flags are
+registered on a flag.FlagSet using the desc struct tag as the help text and the default
+struct tag as the default value shown in the usage. Flag values are parsed using the
+same parsing mechanism as environment variables and defaults.
+*/
+package flags
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	goerrs "errors"
+
+	"go.rtnl.ai/confire/env"
+	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/parse"
+	"go.rtnl.ai/confire/source"
+	"go.rtnl.ai/confire/structs"
+)
+
+const (
+	tagFlag        = "flag"
+	tagIgnored     = "ignored"
+	tagDescription = "desc"
+	tagDefault     = "default"
+)
+
+// Register a flag on the flag set for every field in the specification. The name of
+// the flag is specified by the flag struct tag or is the kebab-cased name of the field
+// (e.g. BindAddr becomes bind-addr). Flags for nested structs are prefixed by the name
+// of the nested struct (e.g. database-url). Set the flag tag to "-" to skip a field. An
+// error is returned if a flag with the same name is already defined on the flag set.
+func Register(fs *flag.FlagSet, spec interface{}) (err error) {
+	var infos []Info
+	if infos, err = Gather(spec); err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if fs.Lookup(info.Name) != nil {
+			return fmt.Errorf("confire: cannot register -%s for field %s: %w", info.Name, info.Field.Name(), errors.ErrDuplicateFlag)
+		}
+		fs.Var(newValue(info.Field), info.Name, info.Field.Tag(tagDescription))
+	}
+	return nil
+}
+
+// Process populates the specified struct from any flags that were registered by the
+// Register function and set on the command line. The flag set must already be parsed.
+func Process(fs *flag.FlagSet, spec interface{}) error {
+	return process(fs, spec, nil)
+}
+
+// Process the flag set, recording the origin of each value that is set with the
+// gathered state (if any).
+func process(fs *flag.FlagSet, spec interface{}, gathered *source.Gathered) (err error) {
+	var infos []Info
+	if infos, err = Gather(spec); err != nil {
+		return err
+	}
+
+	fields := make(map[string]Info, len(infos))
+	for _, info := range infos {
+		fields[info.Name] = info
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		val, ok := f.Value.(*value)
+		if !ok || err != nil {
+			return
+		}
+
+		info, ok := fields[f.Name]
+		if !ok {
+			return
+		}
+
+		if err = parse.ParseField(val.raw, info.Field); err != nil {
+			target := &errors.ParseError{}
+			if goerrs.As(err, &target) {
+				target.Source = "-" + f.Name
+				err = target
+			}
+			return
+		}
+
+		gathered.Set(info.Path, source.Origin{Kind: source.Flag, Location: "-" + f.Name, Value: parse.RedactValue(info.Field, val.raw)})
+	})
+
+	return err
+}
+
+// Source loads the flags that were set on the command line as a configuration source.
+type Source struct {
+	FlagSet *flag.FlagSet
+}
+
+var _ source.Source = Source{}
+
+// Load implements the source.Source interface by processing the flag set.
+func (s Source) Load(_ context.Context, spec interface{}, gathered *source.Gathered) error {
+	if s.FlagSet == nil {
+		return nil
+	}
+	return process(s.FlagSet, spec, gathered)
+}
+
+type Info struct {
+	Name  string         // The name of the command line flag
+	Path  string         // The dotted path of the field in the spec (e.g. Database.URL)
+	Field *structs.Field // The actual field to set the flag value on (along with tags)
+}
+
+// Gather the command line flags for the fields in the specification.
+func Gather(spec interface{}) ([]Info, error) {
+	return gather("", "", spec)
+}
+
+func gather(prefix, path string, spec interface{}) (infos []Info, err error) {
+	var s *structs.Struct
+	if s, err = structs.New(spec); err != nil {
+		return nil, errors.ErrInvalidSpecification
+	}
+
+	if !s.IsPointer() {
+		return nil, errors.ErrInvalidSpecification
+	}
+
+	infos = make([]Info, 0, s.NumField())
+	for _, field := range s.Fields() {
+		// Skip any ignored fields or fields that cannot be set.
+		if !field.CanSet() || isTrue(field.Tag(tagIgnored)) || field.Tag(tagFlag) == "-" {
+			continue
+		}
+
+		// Handle pointers if necessary
+		for field.Kind() == reflect.Ptr && !parse.Registered(field.Type()) {
+			if field.IsNil() {
+				if field.TypeKind() != reflect.Struct || parse.IsDecodable(field) {
+					// nil pointer to a non-struct or to a decodable struct: leave it alone.
+					break
+				}
+
+				// nil pointer to a struct: create a zero-instance
+				if err = field.Init(); err != nil {
+					panic(err)
+				}
+			}
+			field = field.Elem()
+		}
+
+		// Slices and maps of structs cannot be set from a single flag; they are configured
+		// by the file or by the indexed and keyed environment variables instead.
+		if parse.IsStructSlice(field) || parse.IsStructMap(field) {
+			continue
+		}
+
+		info := Info{Name: Name(field), Path: field.Name(), Field: field}
+		if prefix != "" {
+			info.Name = prefix + "-" + info.Name
+		}
+
+		if path != "" {
+			info.Path = path + "." + info.Path
+		}
+
+		// Nested structs are gathered with the name of the struct as the prefix.
+		if field.Kind() == reflect.Struct && !parse.IsDecodable(field) {
+			innerPrefix, innerPath := prefix, path
+			if !field.IsEmbedded() || field.Tag(tagFlag) != "" {
+				innerPrefix = info.Name
+			}
+
+			if !field.IsEmbedded() {
+				innerPath = info.Path
+			}
+
+			var nested []Info
+			if nested, err = gather(innerPrefix, innerPath, field.Pointer()); err != nil {
+				return nil, err
+			}
+			infos = append(infos, nested...)
+			continue
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// Name returns the flag name for the field, either from the flag tag or by converting
+// the CamelCase name of the field into kebab-case.
+func Name(field *structs.Field) string {
+	if name := field.Tag(tagFlag); name != "" {
+		return name
+	}
+	return strings.ToLower(strings.Join(env.SplitWords(field.Name()), "-"))
+}
+
+// value implements flag.Value to capture the raw string from the command line so that
+// it can be parsed into the field when the flags are processed.
+type value struct {
+	raw     string
+	def     string
+	field   *structs.Field
+	isBool  bool
+	secret  bool
+	changed bool
+}
+
+func newValue(field *structs.Field) *value {
+	typ := field.Type()
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	return &value{
+		def:    parse.RedactValue(field, field.Tag(tagDefault)),
+		field:  field,
+		isBool: typ.Kind() == reflect.Bool,
+		secret: parse.IsSecret(field),
+	}
+}
+
+// Set validates that the value can be parsed into the field (using the tags of the field
+// such as sep and kvsep) so that the flag set can report invalid values when the command
+// line is parsed. The value is parsed into a scratch copy of the field so that the spec
+// is not modified until the flags are processed. Secret values are not validated until
+// the flags are processed because the flag set reports the value.
+func (v *value) Set(s string) error {
+	if !v.secret {
+		if err := parse.ParseField(s, v.field.Scratch()); err != nil {
+			return err
+		}
+	}
+
+	v.raw, v.changed = s, true
+	return nil
+}
+
+func (v *value) String() string {
+	if v == nil {
+		return ""
+	}
+
+	if v.changed {
+		if v.secret && v.raw != "" {
+			return parse.Mask
+		}
+		return v.raw
+	}
+	return v.def
+}
+
+// IsBoolFlag allows boolean fields to be specified as -flag rather than -flag=true.
+func (v *value) IsBoolFlag() bool {
+	return v.isBool
+}
+
+func isTrue(s string) bool {
+	b, _ := strconv.ParseBool(s)
+	return b
+}
@@ -0,0 +1,181 @@
+package flags_test
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/confire/assert"
+	"go.rtnl.ai/confire/errors"
+	. "go.rtnl.ai/confire/flags"
+)
+
+type Specification struct {
+	Embedded
+	Debug      bool          `desc:"enable debug mode"`
+	BindAddr   string        `default:":8080" desc:"address to bind to"`
+	TCPHosts   []string      `desc:"hosts to connect to"`
+	Timeout    time.Duration `flag:"timeout-after" default:"10s"`
+	Ignored    string        `ignored:"true"`
+	Skipped    string        `flag:"-"`
+	SomePtr    *int
+	Database   Database
+	DBPtr      *Database `flag:"replica"`
+	unexported string
+}
+
+type Embedded struct {
+	Verbose bool `desc:"verbose output"`
+}
+
+type Database struct {
+	URL      string `desc:"database connection url"`
+	ReadOnly bool
+}
+
+func TestGather(t *testing.T) {
+	spec := &Specification{}
+	infos, err := Gather(spec)
+	assert.Ok(t, err)
+
+	expected := []string{
+		"verbose", "debug", "bind-addr", "tcp-hosts", "timeout-after", "some-ptr",
+		"database-url", "database-read-only", "replica-url", "replica-read-only",
+	}
+
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	assert.Equals(t, expected, names)
+
+	_, err = Gather(Specification{})
+	assert.ErrorIs(t, err, errors.ErrInvalidSpecification)
+}
+
+func TestGatherStructCollections(t *testing.T) {
+	type Peer struct {
+		Host string
+	}
+
+	spec := &struct {
+		Name      string
+		Peers     []Peer
+		Pointers  []*Peer
+		Databases map[string]Database
+		Replicas  map[string]*Database
+		Hosts     []string
+		Labels    map[string]string
+		Endpoints []Peer `encoding:"json"`
+	}{}
+
+	infos, err := Gather(spec)
+	assert.Ok(t, err)
+
+	// Slices and maps of structs are not flags unless they are decoded from a value
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	assert.Equals(t, []string{"name", "hosts", "labels", "endpoints"}, names)
+}
+
+func TestRegister(t *testing.T) {
+	spec := &Specification{}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	assert.Ok(t, Register(fs, spec))
+
+	f := fs.Lookup("bind-addr")
+	assert.Assert(t, f != nil, "expected bind-addr flag to be registered")
+	assert.Equals(t, "address to bind to", f.Usage)
+	assert.Equals(t, ":8080", f.DefValue)
+
+	assert.Assert(t, fs.Lookup("ignored") == nil, "expected ignored field to not be registered")
+	assert.Assert(t, fs.Lookup("skipped") == nil, "expected skipped field to not be registered")
+
+	// Registering the spec twice should return an error rather than panic
+	err := Register(fs, spec)
+	assert.ErrorIs(t, err, errors.ErrDuplicateFlag)
+
+	t.Run("Usage", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		fs.SetOutput(out)
+		fs.PrintDefaults()
+		assert.Assert(t, bytes.Contains(out.Bytes(), []byte("address to bind to (default :8080)")), "expected default in usage")
+	})
+}
+
+func TestProcess(t *testing.T) {
+	spec := &Specification{}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	assert.Ok(t, Register(fs, spec))
+
+	args := []string{
+		"-verbose", "-debug=false", "-bind-addr", "127.0.0.1:443",
+		"-tcp-hosts", "a.example.com,b.example.com", "-timeout-after", "30s",
+		"-some-ptr", "42", "-database-url", "postgres://localhost/test",
+		"-replica-read-only",
+	}
+	assert.Ok(t, fs.Parse(args))
+
+	spec.Debug = true
+	spec.Database.ReadOnly = true
+	assert.Ok(t, Process(fs, spec))
+
+	assert.True(t, spec.Verbose)
+	assert.False(t, spec.Debug)
+	assert.Equals(t, "127.0.0.1:443", spec.BindAddr)
+	assert.Equals(t, []string{"a.example.com", "b.example.com"}, spec.TCPHosts)
+	assert.Equals(t, 30*time.Second, spec.Timeout)
+	assert.Equals(t, 42, *spec.SomePtr)
+	assert.Equals(t, "postgres://localhost/test", spec.Database.URL)
+	assert.True(t, spec.Database.ReadOnly)
+	assert.True(t, spec.DBPtr.ReadOnly)
+	assert.Equals(t, "", spec.DBPtr.URL)
+
+	t.Run("Unset", func(t *testing.T) {
+		// Flags that are not set on the command line do not modify the spec
+		spec := &Specification{BindAddr: "localhost:80"}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		assert.Ok(t, Register(fs, spec))
+		assert.Ok(t, fs.Parse([]string{"-debug"}))
+		assert.Ok(t, Process(fs, spec))
+
+		assert.True(t, spec.Debug)
+		assert.Equals(t, "localhost:80", spec.BindAddr)
+		assert.Equals(t, time.Duration(0), spec.Timeout)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		// Invalid values are reported when the command line is parsed
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(&bytes.Buffer{})
+		assert.Ok(t, Register(fs, &Specification{}))
+
+		err := fs.Parse([]string{"-timeout-after", "forever"})
+		assert.NotOk(t, err)
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		// Values are validated with the sep and kvsep tags of the field
+		spec := &struct {
+			Hosts  []string       `sep:";"`
+			Labels map[string]int `sep:";" kvsep:"="`
+		}{}
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(&bytes.Buffer{})
+		assert.Ok(t, Register(fs, spec))
+
+		assert.Ok(t, fs.Parse([]string{"-hosts", "a:80;b:80", "-labels", "web=80;db=5432"}))
+		assert.Equals(t, 0, len(spec.Labels))
+		assert.Ok(t, Process(fs, spec))
+
+		assert.Equals(t, []string{"a:80", "b:80"}, spec.Hosts)
+		assert.Equals(t, map[string]int{"web": 80, "db": 5432}, spec.Labels)
+
+		err := fs.Parse([]string{"-labels", "web:80"})
+		assert.NotOk(t, err)
+	})
+}
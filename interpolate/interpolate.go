@@ -0,0 +1,288 @@
+/*
+Package interpolate expands shell-style variable references in configuration values
+before they are parsed into the fields of a specification. The following forms are
+supported, where NAME is either an environment variable or the dotted path of another
+field in the specification (e.g. Database.Host):
+
+	${NAME}           the value of NAME or an empty string if it is unset
+	${NAME:-default}  the default if NAME is unset or empty
+	${NAME:?message}  an error with the message if NAME is unset or empty
+	$$                a literal $
+
+References to fields resolve to the value that the field is being set to by the same
+source (expanded recursively) or to the current value of the field otherwise. Fields
+with zero values are treated as unset. A $ that is not followed by { or $ is kept as is.
+*/
+package interpolate
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/parse"
+	"go.rtnl.ai/confire/structs"
+)
+
+// Interpolator expands the variable references in the values of the fields of a spec.
+// An interpolator should be used for a single source so that references to fields
+// resolve to the values that are set by that source.
+type Interpolator struct {
+	lookup   func(key string) (string, bool)
+	fields   map[string]*structs.Field
+	raw      map[string]string
+	expanded map[string]string
+	secret   map[string]bool
+	stack    []string
+}
+
+// New creates an interpolator for the fields of the specification. Environment
+// variables are resolved using the lookup function or os.LookupEnv if it is nil.
+func New(spec interface{}, lookup func(key string) (string, bool)) (*Interpolator, error) {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	i := &Interpolator{
+		lookup:   lookup,
+		fields:   make(map[string]*structs.Field),
+		raw:      make(map[string]string),
+		expanded: make(map[string]string),
+		secret:   make(map[string]bool),
+	}
+
+	if err := i.gather("", spec); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// Collect the fields of the spec by their dotted path without modifying the spec.
+func (i *Interpolator) gather(path string, spec interface{}) (err error) {
+	var s *structs.Struct
+	if s, err = structs.New(spec); err != nil {
+		return errors.ErrInvalidSpecification
+	}
+
+	for _, field := range s.Fields() {
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldPath := field.Name()
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+
+		for field.Kind() == reflect.Ptr && !field.IsNil() {
+			field = field.Elem()
+		}
+
+		if field.Kind() == reflect.Struct && !parse.IsDecodable(field) && field.CanAddr() {
+			if field.IsEmbedded() {
+				fieldPath = path
+			}
+
+			if err = i.gather(fieldPath, field.Pointer()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		i.fields[fieldPath] = field
+	}
+	return nil
+}
+
+// Set the raw value that the source will set on the field at the specified path so
+// that references to the field resolve to the expanded value rather than the current
+// value of the field.
+func (i *Interpolator) Set(path, value string) {
+	i.raw[path] = value
+	delete(i.expanded, path)
+	delete(i.secret, path)
+}
+
+// Field returns the expanded value of the field at the specified path. A CycleError is
+// returned if expanding the value of the field requires the value of the field.
+func (i *Interpolator) Field(path string) (value string, err error) {
+	value, _, err = i.field(path)
+	return value, err
+}
+
+// Secret returns true if the expanded value of the field at the specified path contains
+// the value of a field that is marked as secret (directly or through the references of
+// other fields), in which case the expanded value must be treated as secret as well.
+func (i *Interpolator) Secret(path string) bool {
+	return i.secret[path]
+}
+
+// SetSecret marks the value of the field at the specified path as secret, e.g. because
+// a previous source interpolated the current value of the field from a secret field.
+func (i *Interpolator) SetSecret(path string) {
+	i.secret[path] = true
+}
+
+func (i *Interpolator) field(path string) (_ string, ok bool, err error) {
+	if value, ok := i.expanded[path]; ok {
+		return value, value != "", nil
+	}
+
+	for idx, visited := range i.stack {
+		if visited == path {
+			fields := append(append(make([]string, 0, len(i.stack)-idx+1), i.stack[idx:]...), path)
+			return "", false, &errors.CycleError{Fields: fields}
+		}
+	}
+
+	raw, ok := i.raw[path]
+	if !ok {
+		return i.current(path)
+	}
+
+	i.stack = append(i.stack, path)
+	defer func() { i.stack = i.stack[:len(i.stack)-1] }()
+
+	var value string
+	if value, err = i.Expand(raw); err != nil {
+		return "", false, err
+	}
+
+	i.expanded[path] = value
+	return value, value != "", nil
+}
+
+// Returns the current value of the field, which is unset if it is zero valued.
+func (i *Interpolator) current(path string) (string, bool, error) {
+	field, ok := i.fields[path]
+	if !ok || field.IsZero() {
+		return "", false, nil
+	}
+
+	val := field.Reflect()
+	for val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	return fmt.Sprint(val.Interface()), true, nil
+}
+
+// Expand all of the variable references in the string.
+func (i *Interpolator) Expand(s string) (_ string, err error) {
+	var sb strings.Builder
+	for {
+		idx := strings.IndexByte(s, '$')
+		if idx < 0 {
+			sb.WriteString(s)
+			return sb.String(), nil
+		}
+
+		sb.WriteString(s[:idx])
+		s = s[idx:]
+
+		switch {
+		case strings.HasPrefix(s, "$$"):
+			sb.WriteByte('$')
+			s = s[2:]
+		case strings.HasPrefix(s, "${"):
+			end := closing(s)
+			if end < 0 {
+				return "", fmt.Errorf("%w: missing closing brace", errors.ErrInvalidInterpolation)
+			}
+
+			var value string
+			if value, err = i.variable(s[2:end]); err != nil {
+				return "", err
+			}
+
+			sb.WriteString(value)
+			s = s[end+1:]
+		default:
+			sb.WriteByte('$')
+			s = s[1:]
+		}
+	}
+}
+
+// Resolve the variable expression inside of the braces, applying any modifiers.
+func (i *Interpolator) variable(expr string) (value string, err error) {
+	name, word, op := expr, "", ""
+	if idx := strings.IndexByte(expr, ':'); idx >= 0 {
+		name, op = expr[:idx], expr[idx:]
+		if len(op) > 2 {
+			op, word = op[:2], op[2:]
+		}
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("%w: missing variable name", errors.ErrInvalidInterpolation)
+	}
+
+	var ok bool
+	if value, ok, err = i.resolve(name); err != nil {
+		return "", err
+	}
+
+	switch op {
+	case "":
+		return value, nil
+	case ":-":
+		if !ok || value == "" {
+			return i.Expand(word)
+		}
+		return value, nil
+	case ":?":
+		if !ok || value == "" {
+			if word, err = i.Expand(word); err != nil {
+				return "", err
+			}
+
+			if word == "" {
+				return "", fmt.Errorf("%w: %s", errors.ErrUnsetVariable, name)
+			}
+			return "", fmt.Errorf("%w: %s: %s", errors.ErrUnsetVariable, name, word)
+		}
+		return value, nil
+	default:
+		return "", fmt.Errorf("%w: unknown modifier %q for %s", errors.ErrInvalidInterpolation, op, name)
+	}
+}
+
+// Resolve a name as a reference to a field in the spec or as an environment variable.
+// If the field is secret then the value of the field that is being expanded is secret.
+func (i *Interpolator) resolve(name string) (value string, ok bool, err error) {
+	if field, isField := i.fields[name]; isField {
+		if value, ok, err = i.field(name); err != nil {
+			return "", false, err
+		}
+
+		if ok && len(i.stack) > 0 && (parse.IsSecret(field) || i.secret[name]) {
+			i.secret[i.stack[len(i.stack)-1]] = true
+		}
+		return value, ok, nil
+	}
+
+	value, ok = i.lookup(name)
+	return value, ok, nil
+}
+
+// Returns the index of the brace that closes the ${ at the start of the string.
+func closing(s string) int {
+	depth := 0
+	for idx := 2; idx < len(s); idx++ {
+		switch {
+		case s[idx] == '$' && idx+1 < len(s) && s[idx+1] == '$':
+			idx++
+		case s[idx] == '$' && idx+1 < len(s) && s[idx+1] == '{':
+			depth++
+			idx++
+		case s[idx] == '}':
+			if depth == 0 {
+				return idx
+			}
+			depth--
+		}
+	}
+	return -1
+}
@@ -0,0 +1,215 @@
+package interpolate_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/confire/assert"
+	cerrors "go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/interpolate"
+)
+
+type Specification struct {
+	Embedded
+	Name     string
+	Port     int
+	Timeout  time.Duration
+	DataDir  string
+	Database struct {
+		Host string
+		URL  string
+	}
+	Replica *Database
+}
+
+type Embedded struct {
+	Region string
+}
+
+type Database struct {
+	Host string
+}
+
+var testEnv = map[string]string{
+	"HOME":     "/home/confire",
+	"EMPTY":    "",
+	"USER":     "admin",
+	"DOLLARS":  "$5",
+	"NESTED":   "${HOME}",
+	"MESSAGE":  "is required",
+	"DB_HOST":  "db.example.com",
+	"DATA_DIR": "/var/lib",
+}
+
+func lookup(key string) (string, bool) {
+	val, ok := testEnv[key]
+	return val, ok
+}
+
+func TestExpand(t *testing.T) {
+	spec := &Specification{Port: 8080, Timeout: 5 * time.Second}
+	spec.Database.Host = "localhost"
+
+	interp, err := interpolate.New(spec, lookup)
+	assert.Ok(t, err)
+
+	testCases := []struct {
+		value    string
+		expected string
+	}{
+		{"", ""},
+		{"no variables", "no variables"},
+		{"${HOME}/.myapp", "/home/confire/.myapp"},
+		{"${USER}@${DB_HOST}", "admin@db.example.com"},
+		{"${MISSING}", ""},
+		{"${MISSING:-default}", "default"},
+		{"${EMPTY:-default}", "default"},
+		{"${USER:-default}", "admin"},
+		{"${MISSING:-${HOME}}/data", "/home/confire/data"},
+		{"${MISSING:-${ALSO_MISSING:-nested}}", "nested"},
+		{"${MISSING:-}", ""},
+		{"${USER:?must be set}", "admin"},
+		{"$$HOME", "$HOME"},
+		{"$${HOME}", "${HOME}"},
+		{"pa$word", "pa$word"},
+		{"trailing$", "trailing$"},
+		{"${DOLLARS}", "$5"},
+		{"${NESTED}", "${HOME}"},
+		{"${Database.Host}:${Port}", "localhost:8080"},
+		{"${Timeout}", "5s"},
+		{"${Name:-anonymous}", "anonymous"},
+		{"${Replica.Host:-none}", "none"},
+		{"${MISSING:-$$}", "$"},
+	}
+
+	for _, tc := range testCases {
+		actual, err := interp.Expand(tc.value)
+		assert.Ok(t, err)
+		assert.Equals(t, tc.expected, actual)
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	interp, err := interpolate.New(&Specification{}, lookup)
+	assert.Ok(t, err)
+
+	testCases := []struct {
+		value  string
+		target error
+		msg    string
+	}{
+		{"${HOME", cerrors.ErrInvalidInterpolation, "invalid variable interpolation: missing closing brace"},
+		{"${}", cerrors.ErrInvalidInterpolation, "invalid variable interpolation: missing variable name"},
+		{"${HOME:+alt}", cerrors.ErrInvalidInterpolation, `invalid variable interpolation: unknown modifier ":+" for HOME`},
+		{"${MISSING:?}", cerrors.ErrUnsetVariable, "variable is unset or empty: MISSING"},
+		{"${EMPTY:?${MESSAGE}}", cerrors.ErrUnsetVariable, "variable is unset or empty: EMPTY: is required"},
+		{"${Database.URL:?database url is required}", cerrors.ErrUnsetVariable, "variable is unset or empty: Database.URL: database url is required"},
+	}
+
+	for _, tc := range testCases {
+		_, err := interp.Expand(tc.value)
+		assert.ErrorIs(t, err, tc.target)
+		assert.Equals(t, tc.msg, err.Error())
+	}
+}
+
+func TestFields(t *testing.T) {
+	spec := &Specification{Port: 8080}
+	interp, err := interpolate.New(spec, lookup)
+	assert.Ok(t, err)
+
+	// Values set by the source take precedence over the current values of the fields
+	interp.Set("Database.Host", "${DB_HOST}")
+	interp.Set("Database.URL", "postgres://${Database.Host}:${Port}/${Name:-myapp}")
+	interp.Set("Port", "5432")
+	interp.Set("Region", "us-east-1")
+	interp.Set("DataDir", "${DATA_DIR}/${Region}")
+
+	url, err := interp.Field("Database.URL")
+	assert.Ok(t, err)
+	assert.Equals(t, "postgres://db.example.com:5432/myapp", url)
+
+	dataDir, err := interp.Field("DataDir")
+	assert.Ok(t, err)
+	assert.Equals(t, "/var/lib/us-east-1", dataDir)
+
+	t.Run("Cycle", func(t *testing.T) {
+		interp, err := interpolate.New(&Specification{}, lookup)
+		assert.Ok(t, err)
+
+		interp.Set("Name", "${Database.Host}")
+		interp.Set("Database.Host", "${Database.URL}")
+		interp.Set("Database.URL", "postgres://${Name}")
+
+		_, err = interp.Field("Name")
+		assert.ErrorIs(t, err, cerrors.ErrReferenceCycle)
+
+		var cycle *cerrors.CycleError
+		assert.True(t, errors.As(err, &cycle))
+		assert.Equals(t, []string{"Name", "Database.Host", "Database.URL", "Name"}, cycle.Fields)
+		assert.Equals(t, "reference cycle Name -> Database.Host -> Database.URL -> Name", err.Error())
+
+		// A field that refers to itself is a cycle
+		interp.Set("DataDir", "${DataDir}/data")
+		_, err = interp.Field("DataDir")
+		assert.ErrorIs(t, err, cerrors.ErrReferenceCycle)
+	})
+}
+
+func TestSecretFields(t *testing.T) {
+	spec := &struct {
+		Password string `secret:"true"`
+		DSN      string
+		URL      string
+		Host     string
+	}{}
+
+	interp, err := interpolate.New(spec, lookup)
+	assert.Ok(t, err)
+
+	// Fields that refer to secret fields, directly or indirectly, are secret
+	interp.Set("Password", "hunter2")
+	interp.Set("DSN", "u:${Password}@${Host}")
+	interp.Set("URL", "postgres://${DSN}")
+	interp.Set("Host", "${DB_HOST}")
+
+	for _, path := range []string{"URL", "DSN", "Host", "Password"} {
+		_, err := interp.Field(path)
+		assert.Ok(t, err)
+	}
+
+	assert.True(t, interp.Secret("DSN"))
+	assert.True(t, interp.Secret("URL"))
+	assert.True(t, !interp.Secret("Host"))
+	assert.True(t, !interp.Secret("Password"))
+
+	// Setting a new value clears the secret until the field is expanded again
+	interp.Set("DSN", "u@${Host}")
+	assert.True(t, !interp.Secret("DSN"))
+
+	_, err = interp.Field("DSN")
+	assert.Ok(t, err)
+	assert.True(t, !interp.Secret("DSN"))
+
+	// Current values that were interpolated from secret fields by previous sources
+	interp.SetSecret("Host")
+	interp.Set("DSN", "u@${Host}")
+	_, err = interp.Field("DSN")
+	assert.Ok(t, err)
+	assert.True(t, interp.Secret("DSN"))
+}
+
+func TestNew(t *testing.T) {
+	_, err := interpolate.New("notastruct", nil)
+	assert.ErrorIs(t, err, cerrors.ErrInvalidSpecification)
+
+	// The environment is used if no lookup function is specified
+	t.Setenv("CONFIRE_INTERPOLATE", "fromenv")
+	interp, err := interpolate.New(&Specification{}, nil)
+	assert.Ok(t, err)
+
+	value, err := interp.Expand("${CONFIRE_INTERPOLATE}")
+	assert.Ok(t, err)
+	assert.Equals(t, "fromenv", value)
+}
@@ -1,14 +1,30 @@
 package confire
 
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.rtnl.ai/confire/defaults"
+	"go.rtnl.ai/confire/env"
+	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/file"
+	"go.rtnl.ai/confire/flags"
+	"go.rtnl.ai/confire/source"
+)
+
 type Option func(opts *options) error
 
 var NoDefaults = func(opts *options) error {
 	opts.noDefaults = true
+	opts.configures("NoDefaults")
 	return nil
 }
 
 var NoEnv = func(opts *options) error {
 	opts.noEnv = true
+	opts.configures("NoEnv")
 	return nil
 }
 
@@ -17,10 +33,245 @@ var NoValidate = func(opts *options) error {
 	return nil
 }
 
+// Strict returns an error if there are any environment variables with the prefix that
+// do not refer to a field in the specification, listing each unknown variable along
+// with the closest known variable to help find typos, e.g. MYAPP_TIMOUT.
+var Strict = func(opts *options) error {
+	opts.strict = true
+	opts.configures("Strict")
+	return nil
+}
+
+// WithConfigFile loads the configuration file at the specified path after the
+// defaults are processed and before the environment is processed.
+func WithConfigFile(path string) Option {
+	return func(opts *options) error {
+		opts.configFile = path
+		opts.configures("WithConfigFile")
+		return nil
+	}
+}
+
+// WithConfigName searches for a configuration file with the specified name and any of
+// the supported extensions in the search paths. If not specified, the lowercase prefix
+// is used as the name of the configuration file when searching.
+func WithConfigName(name string) Option {
+	return func(opts *options) error {
+		opts.configName = name
+		opts.configures("WithConfigName")
+		return nil
+	}
+}
+
+// WithSearchPaths specifies the directories to search in order for the configuration
+// file, replacing the default search paths of the current working directory,
+// $XDG_CONFIG_HOME/<prefix>, and /etc/<prefix>.
+func WithSearchPaths(paths ...string) Option {
+	return func(opts *options) error {
+		opts.searchPaths = append(make([]string, 0, len(paths)), paths...)
+		opts.configures("WithSearchPaths")
+		return nil
+	}
+}
+
+// WithConfigFormat specifies the format of the configuration file rather than
+// determining the format from the file extension.
+func WithConfigFormat(format file.Format) Option {
+	return func(opts *options) error {
+		opts.fileOpts = append(opts.fileOpts, file.WithFormat(format))
+		opts.configures("WithConfigFormat")
+		return nil
+	}
+}
+
+// WithEnvOptions specifies options for processing the environment, for example to read
+// values from the files specified by <KEY>_FILE environment variables:
+//
+//	confire.WithEnvOptions(env.WithFiles(), env.WithMaxFileSize(64*1024))
+func WithEnvOptions(opts ...env.Option) Option {
+	return func(o *options) error {
+		o.envOpts = append(o.envOpts, opts...)
+		o.configures("WithEnvOptions")
+		return nil
+	}
+}
+
+// WithInterpolation expands ${VAR} references to environment variables and to other
+// fields of the spec in default values and environment variables before they are
+// parsed. See the interpolate package for the supported syntax.
+func WithInterpolation() Option {
+	return func(o *options) error {
+		o.defaultOpts = append(o.defaultOpts, defaults.WithInterpolation())
+		o.envOpts = append(o.envOpts, env.WithInterpolation())
+		o.configures("WithInterpolation")
+		return nil
+	}
+}
+
+// WithLookuper specifies how environment variables are looked up rather than looking
+// them up in the environment of the process. This allows several specifications to be
+// processed concurrently from isolated environments, for example:
+//
+//	confire.WithLookuper(env.MapLookuper{"MYAPP_PORT": "8000"})
+func WithLookuper(lookuper env.Lookuper) Option {
+	return func(o *options) error {
+		o.lookuper = lookuper
+		o.defaultOpts = append(o.defaultOpts, defaults.WithLookuper(lookuper))
+		o.envOpts = append(o.envOpts, env.WithLookuper(lookuper))
+		o.configures("WithLookuper")
+		return nil
+	}
+}
+
+// WithFlags loads any flags set on the command line after the environment is
+// processed so that flags take precedence over environment variables. The flags must be
+// registered on the flag set with flags.Register and parsed before processing.
+func WithFlags(fs *flag.FlagSet) Option {
+	return func(opts *options) error {
+		opts.flags = fs
+		opts.configures("WithFlags")
+		return nil
+	}
+}
+
+// WithOrigins records the origin of each field value that is set while processing into
+// the specified map, keyed by the dotted path of the field (e.g. Database.URL). Fields
+// that were not set by any source (e.g. left at their zero value) are not recorded.
+func WithOrigins(origins Origins) Option {
+	return func(opts *options) error {
+		opts.origins = origins
+		return nil
+	}
+}
+
+// WithWatchInterval specifies how often a Watcher checks the configuration file for
+// changes (DefaultWatchInterval if not specified).
+func WithWatchInterval(interval time.Duration) Option {
+	return func(opts *options) error {
+		opts.watchInterval = interval
+		return nil
+	}
+}
+
+// WithSources specifies the sources to load the configuration from in order of
+// precedence, e.g. values from later sources override values from earlier sources.
+// This replaces the default sources (defaults, the configuration file, and the
+// environment) so they must be included if they're still required, for example:
+//
+//	confire.WithSources(defaults.Source{}, file.Source{Path: path}, env.Source{}, vault)
+//
+// Options that configure the default sources (e.g. NoEnv, Strict, WithConfigFile, or
+// WithFlags) cannot be used with WithSources since there are no default sources for
+// them to configure; specify the options of each source on the source instead.
+func WithSources(sources ...Source) Option {
+	return func(opts *options) error {
+		opts.sources = append(make([]source.Source, 0, len(sources)), sources...)
+		return nil
+	}
+}
+
 type options struct {
-	noDefaults bool
-	noEnv      bool
-	noValidate bool
+	noDefaults    bool
+	noEnv         bool
+	noValidate    bool
+	strict        bool
+	configFile    string
+	configName    string
+	searchPaths   []string
+	defaultOpts   []defaults.Option
+	fileOpts      []file.Option
+	envOpts       []env.Option
+	lookuper      env.Lookuper
+	flags         *flag.FlagSet
+	origins       source.Origins
+	watchInterval time.Duration
+	sources       []source.Source
+	configured    []string
+}
+
+// Records the name of an option that configures the default sources so that it can be
+// reported if the default sources are replaced by WithSources.
+func (o *options) configures(name string) {
+	o.configured = append(o.configured, name)
+}
+
+// Returns the sources to load in order of precedence. Unless the sources have been
+// specified by the user, the defaults are loaded first, then the configuration file,
+// then the environment, and finally the command line flags.
+func (o *options) pipeline(prefix string) []source.Source {
+	if o.sources != nil {
+		return o.sources
+	}
+
+	sources := make([]source.Source, 0, 4)
+	if !o.noDefaults {
+		sources = append(sources, defaults.Source{Options: o.defaultOpts})
+	}
+
+	if path := o.configPath(prefix); path != "" {
+		sources = append(sources, file.Source{Path: path, Options: o.fileOpts})
+	}
+
+	if !o.noEnv {
+		// Report all of the invalid environment variables at once
+		envOpts := append([]env.Option{env.WithAllErrors()}, o.envOpts...)
+		if o.strict {
+			// The configuration file variable is known even though it's not a field
+			envOpts = append(envOpts, env.WithStrict(configEnv(prefix)))
+		}
+		sources = append(sources, env.Source{Options: envOpts})
+	}
+
+	if o.flags != nil {
+		sources = append(sources, flags.Source{FlagSet: o.flags})
+	}
+	return sources
+}
+
+// Determine the path of the configuration file to load, if any. Configuration files
+// are only loaded if one of the configuration file options is specified. The
+// <PREFIX>_CONFIG environment variable takes precedence over the configured file,
+// which takes precedence over the first file found in the search paths.
+func (o *options) configPath(prefix string) string {
+	if o.configFile == "" && o.configName == "" && o.searchPaths == nil {
+		return ""
+	}
+
+	lookuper := o.lookuper
+	if lookuper == nil {
+		lookuper = env.OSLookuper{}
+	}
+
+	if path, _ := lookuper.Lookup(configEnv(prefix)); path != "" {
+		return path
+	}
+
+	if o.configFile != "" {
+		return o.configFile
+	}
+
+	name := o.configName
+	if name == "" {
+		if name = strings.ToLower(prefix); name == "" {
+			name = "config"
+		}
+	}
+
+	paths := o.searchPaths
+	if paths == nil {
+		paths = file.SearchPaths(prefix, lookuper.Lookup)
+	}
+
+	path, _ := file.Find(name, paths...)
+	return path
+}
+
+// Returns the environment variable that specifies the configuration file path.
+func configEnv(prefix string) string {
+	if prefix == "" {
+		return "CONFIG"
+	}
+	return strings.ToUpper(prefix) + "_CONFIG"
 }
 
 func makeOptions(opts ...Option) (*options, error) {
@@ -30,5 +281,9 @@ func makeOptions(opts ...Option) (*options, error) {
 			return nil, err
 		}
 	}
+
+	if conf.sources != nil && len(conf.configured) > 0 {
+		return nil, fmt.Errorf("confire: cannot use %s with WithSources: %w", strings.Join(conf.configured, ", "), errors.ErrConflictingOptions)
+	}
 	return conf, nil
 }
@@ -18,9 +18,48 @@ type Setter interface {
 	Set(value string) error
 }
 
-// Returns true if the field implements one of the decodable interfaces.
+// Returns true if the field implements one of the decodable interfaces, has a registered
+// parser, or is decoded from JSON because it has the encoding:"json" struct tag.
 func IsDecodable(field *structs.Field) bool {
-	return DecoderFrom(field) != nil || SetterFrom(field) != nil || TextUnmarshalerFrom(field) != nil || BinaryUnmarshalerFrom(field) != nil
+	return IsJSON(field) || isRegistered(field.Type()) || DecoderFrom(field) != nil || SetterFrom(field) != nil || TextUnmarshalerFrom(field) != nil || BinaryUnmarshalerFrom(field) != nil
+}
+
+// Returns true if the value implements one of the decodable interfaces or has a
+// registered parser.
+func IsDecodableValue(field reflect.Value) bool {
+	return isRegistered(field.Type()) || DecoderFromValue(field) != nil || SetterFromValue(field) != nil || TextUnmarshalerFromValue(field) != nil || BinaryUnmarshalerFromValue(field) != nil
+}
+
+// IsStructSlice returns true if the field is a slice of structs (or of pointers to
+// structs) whose elements are configured as nested specifications rather than decoded
+// as single values, e.g. a []Peer but not a []url.URL or a slice with a JSON encoding.
+func IsStructSlice(field *structs.Field) bool {
+	return field.Kind() == reflect.Slice && hasStructElems(field)
+}
+
+// IsStructArray returns true if the field is an array of structs (or of pointers to
+// structs) whose elements are configured as nested specifications.
+func IsStructArray(field *structs.Field) bool {
+	return field.Kind() == reflect.Array && hasStructElems(field)
+}
+
+// IsStructMap returns true if the field is a map of structs (or of pointers to structs)
+// whose values are configured as nested specifications.
+func IsStructMap(field *structs.Field) bool {
+	return field.Kind() == reflect.Map && hasStructElems(field)
+}
+
+func hasStructElems(field *structs.Field) bool {
+	elem := field.Type().Elem()
+	base := elem
+	if base.Kind() == reflect.Ptr {
+		base = base.Elem()
+	}
+
+	if base.Kind() != reflect.Struct {
+		return false
+	}
+	return !IsDecodable(field) && !IsDecodableValue(reflect.New(elem).Elem())
 }
 
 // Attempts to get a Decoder variable from the specified field.
@@ -164,3 +164,45 @@ func TestBinaryUnmarshalerFrom(t *testing.T) {
 		}
 	}
 }
+
+func TestStructCollections(t *testing.T) {
+	s, err := structs.New(&struct {
+		Peers     []DoesNot
+		Pointers  []*DoesNot
+		Decoded   []Does
+		JSON      []DoesNot `encoding:"json"`
+		Names     []string
+		Array     [2]DoesNot
+		Databases map[string]DoesNot
+		Replicas  map[string]*DoesNot
+		Labels    map[string]string
+	}{})
+	assert.Ok(t, err)
+
+	testCases := []struct {
+		name   string
+		slice  bool
+		array  bool
+		mapped bool
+	}{
+		{"Peers", true, false, false},
+		{"Pointers", true, false, false},
+		{"Decoded", false, false, false},
+		{"JSON", false, false, false},
+		{"Names", false, false, false},
+		{"Array", false, true, false},
+		{"Databases", false, false, true},
+		{"Replicas", false, false, true},
+		{"Labels", false, false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			field, err := s.Field(tc.name)
+			assert.Ok(t, err)
+			assert.Equals(t, tc.slice, IsStructSlice(field))
+			assert.Equals(t, tc.array, IsStructArray(field))
+			assert.Equals(t, tc.mapped, IsStructMap(field))
+		})
+	}
+}
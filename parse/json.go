@@ -0,0 +1,47 @@
+package parse
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"go.rtnl.ai/confire/structs"
+)
+
+const (
+	tagEncoding  = "encoding"
+	encodingJSON = "json"
+)
+
+// IsJSON returns true if the field has the encoding:"json" struct tag, in which case
+// the value of the field is decoded as JSON, e.g. to set a struct, a nested slice, or a
+// map[string][]string from a single environment variable.
+func IsJSON(field *structs.Field) bool {
+	return strings.EqualFold(field.Tag(tagEncoding), encodingJSON)
+}
+
+// Returns true if the value of a slice or map without the encoding tag should be
+// decoded as JSON because it is a valid JSON array or object respectively.
+func isJSONValue(value string, kind reflect.Kind) bool {
+	value = strings.TrimSpace(value)
+	switch kind {
+	case reflect.Slice:
+		return strings.HasPrefix(value, "[") && json.Valid([]byte(value))
+	case reflect.Map:
+		return strings.HasPrefix(value, "{") && json.Valid([]byte(value))
+	default:
+		return false
+	}
+}
+
+// Decode the JSON value into a new value of the field's type so that the field is
+// unchanged if the value cannot be decoded.
+func decodeJSON(value string, field reflect.Value) error {
+	ptr := reflect.New(field.Type())
+	if err := json.Unmarshal([]byte(value), ptr.Interface()); err != nil {
+		return err
+	}
+
+	field.Set(ptr.Elem())
+	return nil
+}
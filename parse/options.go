@@ -0,0 +1,95 @@
+package parse
+
+import (
+	"sync"
+
+	"go.rtnl.ai/confire/errors"
+)
+
+const (
+	tagSep    = "sep"
+	tagKVSep  = "kvsep"
+	tagQuoted = "quoted"
+)
+
+// Option configures how slices and maps are parsed package-wide; see Configure.
+type Option func(opts *options) error
+
+// WithSeparator sets the separator between the elements of slices and between the
+// key/value pairs of maps (a comma by default). Fields can override the separator with
+// the sep struct tag, e.g. sep:";".
+func WithSeparator(sep string) Option {
+	return func(opts *options) error {
+		if sep == "" {
+			return errors.ErrEmptySeparator
+		}
+		opts.sep = sep
+		return nil
+	}
+}
+
+// WithKVSeparator sets the separator between the keys and values of maps (a colon by
+// default). Fields can override the separator with the kvsep struct tag, e.g. kvsep:"=".
+func WithKVSeparator(kvsep string) Option {
+	return func(opts *options) error {
+		if kvsep == "" {
+			return errors.ErrEmptySeparator
+		}
+		opts.kvsep = kvsep
+		return nil
+	}
+}
+
+// WithQuoted enables or disables quoted values as in CSV, e.g. "a,b",c is parsed as the
+// two elements a,b and c; a quote is escaped in a quoted value by another quote. Fields
+// can override the mode with the quoted struct tag, e.g. quoted:"true".
+func WithQuoted(quoted bool) Option {
+	return func(opts *options) error {
+		opts.quoted = quoted
+		return nil
+	}
+}
+
+type options struct {
+	sep    string
+	kvsep  string
+	quoted bool
+}
+
+var (
+	mu       sync.RWMutex
+	defaults = options{sep: ",", kvsep: ":"}
+)
+
+// Configure sets the package-wide options for parsing slices and maps, which are used
+// by every field that does not override them with struct tags. The options are applied
+// in order on top of the current options; if any option is invalid then none are set.
+func Configure(opts ...Option) (err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	conf := defaults
+	for _, opt := range opts {
+		if err = opt(&conf); err != nil {
+			return err
+		}
+	}
+
+	defaults = conf
+	return nil
+}
+
+// Reset the package-wide options to their defaults.
+func Reset() {
+	mu.Lock()
+	defaults = options{sep: ",", kvsep: ":"}
+	mu.Unlock()
+}
+
+// Returns a copy of the package-wide options.
+func current() *options {
+	mu.RLock()
+	defer mu.RUnlock()
+	conf := defaults
+	return &conf
+}
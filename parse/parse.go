@@ -17,6 +17,19 @@ import (
 )
 
 func Parse(value string, field reflect.Value) error {
+	// Registered parsers take precedence over the decoder interfaces.
+	if p, err := parseRegistered(value, field); p != nil {
+		if err != nil {
+			return &errors.ParseError{
+				Source: "Parser",
+				Type:   p.name,
+				Value:  value,
+				Err:    err,
+			}
+		}
+		return nil
+	}
+
 	// Attempt to use the decoder, setter, and unmarshalers to parse the field.
 	if decoder := DecoderFromValue(field); decoder != nil {
 		if err := decoder.Decode(value); err != nil {
@@ -69,7 +82,12 @@ func Parse(value string, field reflect.Value) error {
 		return nil
 	}
 
-	if err := parse(value, field); err != nil {
+	if err := parse(value, field, current()); err != nil {
+		// Errors parsing the elements of slices and maps are already parse errors
+		if perr, ok := err.(*errors.ParseError); ok {
+			return perr
+		}
+
 		return &errors.ParseError{
 			Type:  field.Type().Name(),
 			Value: value,
@@ -79,8 +97,41 @@ func Parse(value string, field reflect.Value) error {
 	return nil
 }
 
-// ParseField parses the given type from the field and sets it.
+// ParseField parses the given type from the field and sets it. If the field is secret,
+// the value is redacted from any parse errors that are returned.
 func ParseField(value string, field *structs.Field) error {
+	return RedactError(field, parseField(value, field))
+}
+
+func parseField(value string, field *structs.Field) error {
+	// Fields with the encoding:"json" tag are decoded from JSON before any decoders.
+	if IsJSON(field) {
+		if err := decodeJSON(value, field.Reflect()); err != nil {
+			return &errors.ParseError{
+				Source: "JSON",
+				Field:  field.Name(),
+				Type:   field.Type().String(),
+				Value:  value,
+				Err:    err,
+			}
+		}
+		return nil
+	}
+
+	// Registered parsers take precedence over the decoder interfaces.
+	if p, err := parseRegistered(value, field.Reflect()); p != nil {
+		if err != nil {
+			return &errors.ParseError{
+				Source: "Parser",
+				Field:  field.Name(),
+				Type:   p.name,
+				Value:  value,
+				Err:    err,
+			}
+		}
+		return nil
+	}
+
 	for field.Kind() == reflect.Ptr {
 		if field.IsNil() {
 			if field.TypeKind() != reflect.Struct {
@@ -150,7 +201,14 @@ func ParseField(value string, field *structs.Field) error {
 		return nil
 	}
 
-	if err := parse(value, field.Reflect()); err != nil {
+	if err := parse(value, field.Reflect(), fieldOptions(field)); err != nil {
+		// Errors parsing the elements of slices and maps report the path of the element
+		if perr, ok := err.(*errors.ParseError); ok {
+			perr.Field = field.Name()
+			perr.Path = field.Name() + perr.Path
+			return perr
+		}
+
 		return &errors.ParseError{
 			Field: field.Name(),
 			Type:  field.Type().Name(),
@@ -161,7 +219,24 @@ func ParseField(value string, field *structs.Field) error {
 	return nil
 }
 
-func parse(value string, field reflect.Value) (err error) {
+// Returns the package-wide options overridden by the struct tags of the field.
+func fieldOptions(field *structs.Field) *options {
+	conf := current()
+	if sep := field.Tag(tagSep); sep != "" {
+		conf.sep = sep
+	}
+
+	if kvsep := field.Tag(tagKVSep); kvsep != "" {
+		conf.kvsep = kvsep
+	}
+
+	if quoted, err := strconv.ParseBool(field.Tag(tagQuoted)); err == nil {
+		conf.quoted = quoted
+	}
+	return conf
+}
+
+func parse(value string, field reflect.Value, conf *options) (err error) {
 	typ := field.Type()
 
 	// If this is a pointer to another value, make sure that value is allocated.
@@ -218,35 +293,59 @@ func parse(value string, field reflect.Value) (err error) {
 		sl := reflect.MakeSlice(typ, 0, 0)
 		if typ.Elem().Kind() == reflect.Uint8 {
 			sl = reflect.ValueOf(toBytes(value))
+		} else if isJSONValue(value, reflect.Slice) {
+			return decodeJSON(value, field)
 		} else if strings.TrimSpace(value) != "" {
-			vals := strings.Split(value, ",")
+			var vals []string
+			if vals, err = split(value, conf.sep, conf.quoted); err != nil {
+				return err
+			}
+
 			sl = reflect.MakeSlice(typ, len(vals), len(vals))
 			for i, val := range vals {
-				if err = Parse(val, sl.Index(i)); err != nil {
+				if val, err = unquote(val, conf.quoted); err != nil {
 					return err
 				}
+
+				if err = Parse(val, sl.Index(i)); err != nil {
+					return elementError(fmt.Sprintf("[%d]", i), err)
+				}
 			}
 		}
 		field.Set(sl)
 
 	case reflect.Map:
+		if isJSONValue(value, reflect.Map) {
+			return decodeJSON(value, field)
+		}
+
 		mp := reflect.MakeMap(typ)
 		if strings.TrimSpace(value) != "" {
-			pairs := strings.Split(value, ",")
+			var pairs []string
+			if pairs, err = split(value, conf.sep, conf.quoted); err != nil {
+				return err
+			}
+
 			for _, pair := range pairs {
-				kvpair := strings.Split(pair, ":")
-				if len(kvpair) != 2 {
+				var kvpair []string
+				if kvpair, err = split(pair, conf.kvsep, conf.quoted); err != nil || len(kvpair) != 2 {
 					return fmt.Errorf("invalid map item: %q", pair)
 				}
 
+				for i := range kvpair {
+					if kvpair[i], err = unquote(kvpair[i], conf.quoted); err != nil {
+						return err
+					}
+				}
+
 				k := reflect.New(typ.Key()).Elem()
 				if err = Parse(kvpair[0], k); err != nil {
-					return nil
+					return elementError(fmt.Sprintf("[%s]", kvpair[0]), err)
 				}
 
 				v := reflect.New(typ.Elem()).Elem()
 				if err = Parse(kvpair[1], v); err != nil {
-					return nil
+					return elementError(fmt.Sprintf("[%s]", kvpair[0]), err)
 				}
 
 				mp.SetMapIndex(k, v)
@@ -258,6 +357,61 @@ func parse(value string, field reflect.Value) (err error) {
 	return nil
 }
 
+// Adds the index or key of the element of a slice or map to the path of the parse error
+// returned when parsing the element, e.g. [2] or [red], so that the path of an element
+// of a nested collection includes each of the indices or keys.
+func elementError(index string, err error) error {
+	if perr, ok := err.(*errors.ParseError); ok {
+		perr.Path = index + perr.Path
+		return perr
+	}
+	return err
+}
+
+// Split the value by the separator. If quoted, separators between double quotes do not
+// split the value, as in CSV, and the quotes are kept so that the elements can be split
+// further (e.g. maps by the key/value separator) before they are unquoted.
+func split(value, sep string, quoted bool) (elems []string, err error) {
+	if !quoted {
+		return strings.Split(value, sep), nil
+	}
+
+	inquote, start := false, 0
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '"':
+			inquote = !inquote
+		case !inquote && strings.HasPrefix(value[i:], sep):
+			elems = append(elems, value[start:i])
+			start = i + len(sep)
+			i = start - 1
+		}
+	}
+
+	if inquote {
+		return nil, fmt.Errorf("unterminated quoted element")
+	}
+	return append(elems, value[start:]), nil
+}
+
+// Unquote an element that is enclosed in double quotes if quoted, in which case a quote
+// in the element is escaped by another quote. Elements without quotes are unchanged.
+func unquote(elem string, quoted bool) (string, error) {
+	if !quoted || !strings.Contains(elem, `"`) {
+		return elem, nil
+	}
+
+	if len(elem) < 2 || elem[0] != '"' || elem[len(elem)-1] != '"' {
+		return "", fmt.Errorf("invalid quoted element: %q", elem)
+	}
+
+	inner := elem[1 : len(elem)-1]
+	if strings.Contains(strings.ReplaceAll(inner, `""`, ""), `"`) {
+		return "", fmt.Errorf("invalid quoted element: %q", elem)
+	}
+	return strings.ReplaceAll(inner, `""`, `"`), nil
+}
+
 func toBytes(v string) []byte {
 	if data, err := base64.StdEncoding.DecodeString(v); err == nil {
 		return data
@@ -3,14 +3,21 @@ package parse_test
 import (
 	"bytes"
 	"encoding/hex"
+	goerrs "errors"
 	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
 	"net/url"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"go.rtnl.ai/confire/assert"
+	"go.rtnl.ai/confire/errors"
 	"go.rtnl.ai/confire/parse"
 	"go.rtnl.ai/confire/structs"
 )
@@ -176,3 +183,330 @@ func (e *CustomURL) Decode(value string) (err error) {
 	e.Value, err = url.Parse(value)
 	return err
 }
+
+func TestParseElementErrors(t *testing.T) {
+	spec := &struct {
+		Colors  map[string]int
+		Ports   []int
+		Weights map[int]float64
+		Secrets map[string]int `secret:"true"`
+	}{}
+
+	s, err := structs.New(spec)
+	assert.Ok(t, err)
+
+	testCases := []struct {
+		field string
+		value string
+		path  string
+		typ   string
+		elem  string
+	}{
+		{"Colors", "blue:1,red:abc", "Colors[red]", "int", "abc"},
+		{"Ports", "80,443,http", "Ports[2]", "int", "http"},
+		{"Weights", "1:0.5,two:0.25", "Weights[two]", "int", "two"},
+		{"Weights", "1:0.5,2:half", "Weights[2]", "float64", "half"},
+		{"Secrets", "alice:sk-secret", "Secrets[alice]", "int", parse.Mask},
+	}
+
+	for _, tc := range testCases {
+		field, err := s.Field(tc.field)
+		assert.Ok(t, err)
+
+		err = parse.ParseField(tc.value, field)
+		assert.NotOk(t, err)
+
+		target := &errors.ParseError{}
+		assert.True(t, goerrs.As(err, &target))
+		assert.Equals(t, tc.field, target.Field)
+		assert.Equals(t, tc.path, target.Path)
+		assert.Equals(t, tc.typ, target.Type)
+		assert.Equals(t, tc.elem, target.Value)
+		assert.True(t, strings.HasPrefix(err.Error(), "confire: could not parse "+tc.path+" from"))
+	}
+
+	// Maps are not partially set
+	assert.Equals(t, map[string]int(nil), spec.Colors)
+
+	// The path of an element of a nested collection includes each index
+	target := &errors.ParseError{}
+	err = parse.Parse("1,x", reflect.ValueOf(&[][]int{}))
+	assert.True(t, goerrs.As(err, &target))
+	assert.Equals(t, "[1][0]", target.Path)
+	assert.Equals(t, "x", target.Value)
+}
+
+func TestParseJSON(t *testing.T) {
+	type Endpoint struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+
+	spec := &struct {
+		Endpoint  Endpoint            `encoding:"json"`
+		Pointer   *Endpoint           `encoding:"json"`
+		Routes    map[string][]string `encoding:"json"`
+		Matrix    [][]int             `encoding:"json"`
+		Detected  map[string][]string
+		Ports     []int
+		Names     []string
+		Brackets  []string
+		Endpoints []Endpoint
+	}{}
+
+	s, err := structs.New(spec)
+	assert.Ok(t, err)
+
+	testCases := []struct {
+		field    string
+		value    string
+		expected any
+	}{
+		{"Endpoint", `{"host": "localhost", "port": 8080}`, Endpoint{Host: "localhost", Port: 8080}},
+		{"Pointer", `{"host": "example.com"}`, &Endpoint{Host: "example.com"}},
+		{"Routes", `{"/api": ["GET", "POST"]}`, map[string][]string{"/api": {"GET", "POST"}}},
+		{"Matrix", `[[1, 2], [3]]`, [][]int{{1, 2}, {3}}},
+		{"Detected", ` {"a": ["b", "c"]}`, map[string][]string{"a": {"b", "c"}}},
+		{"Ports", `[80, 443]`, []int{80, 443}},
+		{"Names", `["a,b", "c"]`, []string{"a,b", "c"}},
+		{"Brackets", `[a],b`, []string{"[a]", "b"}},
+		{"Endpoints", `[{"host": "a"}, {"host": "b", "port": 2}]`, []Endpoint{{Host: "a"}, {Host: "b", Port: 2}}},
+	}
+
+	for _, tc := range testCases {
+		field, err := s.Field(tc.field)
+		assert.Ok(t, err)
+		assert.Ok(t, parse.ParseField(tc.value, field))
+		assert.Equals(t, tc.expected, field.Value())
+	}
+
+	// The field is not changed if the JSON is invalid
+	field, err := s.Field("Endpoint")
+	assert.Ok(t, err)
+
+	err = parse.ParseField(`{"host": 42}`, field)
+	target := &errors.ParseError{}
+	assert.True(t, goerrs.As(err, &target))
+	assert.Equals(t, "JSON", target.Source)
+	assert.Equals(t, "parse_test.Endpoint", target.Type)
+	assert.Equals(t, Endpoint{Host: "localhost", Port: 8080}, spec.Endpoint)
+
+	// Fields with the encoding tag are decoded from a single value
+	assert.True(t, parse.IsJSON(field))
+	assert.True(t, parse.IsDecodable(field))
+}
+
+func TestSeparators(t *testing.T) {
+	spec := &struct {
+		Hosts   []string          `sep:";"`
+		Labels  map[string]string `sep:";" kvsep:"="`
+		Quoted  []string          `quoted:"true"`
+		Headers map[string]string `quoted:"true"`
+		Ports   []int             `sep:" "`
+		Default []string
+	}{}
+
+	s, err := structs.New(spec)
+	assert.Ok(t, err)
+
+	testCases := []struct {
+		field    string
+		value    string
+		expected any
+	}{
+		{"Hosts", "http://a:80;http://b:80", []string{"http://a:80", "http://b:80"}},
+		{"Labels", "url=http://a:80;env=prod", map[string]string{"url": "http://a:80", "env": "prod"}},
+		{"Quoted", `"a,b",c`, []string{"a,b", "c"}},
+		{"Quoted", `"say ""hi""",,""`, []string{`say "hi"`, "", ""}},
+		{"Quoted", `plain,"",x`, []string{"plain", "", "x"}},
+		{"Headers", `"http://a":"b,c",d:e`, map[string]string{"http://a": "b,c", "d": "e"}},
+		{"Ports", "80 443", []int{80, 443}},
+		{"Default", `"a,b",c`, []string{`"a`, `b"`, "c"}},
+	}
+
+	for _, tc := range testCases {
+		field, err := s.Field(tc.field)
+		assert.Ok(t, err)
+		assert.Ok(t, parse.ParseField(tc.value, field))
+		assert.Equals(t, tc.expected, field.Value())
+	}
+
+	errorCases := []struct {
+		field string
+		value string
+		msg   string
+	}{
+		{"Quoted", `"unterminated,b`, "unterminated quoted element"},
+		{"Quoted", `"a"b,c`, `invalid quoted element: "\"a\"b"`},
+		{"Quoted", `a"b"c`, `invalid quoted element: "a\"b\"c"`},
+		{"Quoted", `"a"b"`, "unterminated quoted element"},
+		{"Headers", `"a:b",c`, `invalid map item: "\"a:b\""`},
+		{"Labels", "url:http", `invalid map item: "url:http"`},
+	}
+
+	for _, tc := range errorCases {
+		field, err := s.Field(tc.field)
+		assert.Ok(t, err)
+
+		err = parse.ParseField(tc.value, field)
+		assert.NotOk(t, err)
+		assert.True(t, strings.HasSuffix(err.Error(), tc.msg))
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	t.Cleanup(parse.Reset)
+
+	spec := &struct {
+		Names  []string
+		Tagged []string          `sep:","`
+		Colors map[string]string `quoted:"false"`
+	}{}
+
+	s, err := structs.New(spec)
+	assert.Ok(t, err)
+
+	// The package-wide options are used unless they are overridden by a tag
+	assert.Ok(t, parse.Configure(parse.WithSeparator("|"), parse.WithKVSeparator("="), parse.WithQuoted(true)))
+
+	names, _ := s.Field("Names")
+	assert.Ok(t, parse.ParseField(`a|"b|c"`, names))
+	assert.Equals(t, []string{"a", "b|c"}, spec.Names)
+
+	tagged, _ := s.Field("Tagged")
+	assert.Ok(t, parse.ParseField(`a|b,c`, tagged))
+	assert.Equals(t, []string{"a|b", "c"}, spec.Tagged)
+
+	colors, _ := s.Field("Colors")
+	assert.Ok(t, parse.ParseField(`"red"=ff0000|blue=0000ff`, colors))
+	assert.Equals(t, map[string]string{`"red"`: "ff0000", "blue": "0000ff"}, spec.Colors)
+
+	var ports []int
+	assert.Ok(t, parse.Parse("80|443", reflect.ValueOf(&ports)))
+	assert.Equals(t, []int{80, 443}, ports)
+
+	// Invalid options do not change the package-wide options
+	assert.ErrorIs(t, parse.Configure(parse.WithSeparator(";"), parse.WithKVSeparator("")), errors.ErrEmptySeparator)
+	assert.Ok(t, parse.Parse("80|443", reflect.ValueOf(&ports)))
+	assert.Equals(t, []int{80, 443}, ports)
+
+	parse.Reset()
+	assert.Ok(t, parse.Parse("80,443", reflect.ValueOf(&ports)))
+	assert.Equals(t, []int{80, 443}, ports)
+}
+
+func TestRegistry(t *testing.T) {
+	spec := &struct {
+		IP       net.IP
+		IPs      []net.IP
+		Network  net.IPNet
+		Endpoint *url.URL
+		Pattern  *regexp.Regexp
+		Zone     *time.Location
+		Addr     netip.Addr
+		AddrPort netip.AddrPort
+		Big      big.Int
+		Float    *big.Float
+	}{}
+
+	s, err := structs.New(spec)
+	assert.Ok(t, err)
+
+	values := map[string]string{
+		"IP":       "10.0.0.1",
+		"IPs":      "10.0.0.1,::1",
+		"Network":  "10.0.0.0/8",
+		"Endpoint": "https://rotational.io/confire?q=1",
+		"Pattern":  "^[a-z]+$",
+		"Zone":     "America/New_York",
+		"Addr":     "192.168.1.1",
+		"AddrPort": "192.168.1.1:8080",
+		"Big":      "123456789012345678901234567890",
+		"Float":    "1.5e100",
+	}
+
+	for name, value := range values {
+		field, err := s.Field(name)
+		assert.Ok(t, err)
+		assert.Ok(t, parse.ParseField(value, field))
+		assert.Equals(t, name != "IPs", parse.IsDecodable(field))
+	}
+
+	assert.Equals(t, net.ParseIP("10.0.0.1"), spec.IP)
+	assert.Equals(t, []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("::1")}, spec.IPs)
+	assert.Equals(t, "10.0.0.0/8", spec.Network.String())
+	assert.Equals(t, "rotational.io", spec.Endpoint.Host)
+	assert.True(t, spec.Pattern.MatchString("confire"))
+	assert.Equals(t, "America/New_York", spec.Zone.String())
+	assert.Equals(t, netip.MustParseAddr("192.168.1.1"), spec.Addr)
+	assert.Equals(t, uint16(8080), spec.AddrPort.Port())
+	assert.Equals(t, "123456789012345678901234567890", spec.Big.String())
+	assert.Equals(t, "1.5e+100", spec.Float.Text('g', 10))
+
+	errorCases := map[string]string{
+		"IP":       "10.0.0",
+		"Network":  "10.0.0.0",
+		"Endpoint": "://missing",
+		"Pattern":  "[a-z",
+		"Zone":     "Mars/Olympus_Mons",
+		"Addr":     "localhost",
+		"Big":      "12abc",
+		"Float":    "notafloat",
+	}
+
+	for name, value := range errorCases {
+		field, err := s.Field(name)
+		assert.Ok(t, err)
+
+		err = parse.ParseField(value, field)
+		target := &errors.ParseError{}
+		assert.True(t, goerrs.As(err, &target))
+		assert.Equals(t, "Parser", target.Source)
+		assert.Equals(t, name, target.Field)
+	}
+
+	name, ok := parse.RegisteredName(reflect.TypeOf(spec.Endpoint))
+	assert.True(t, ok)
+	assert.Equals(t, "URL", name)
+
+	_, ok = parse.RegisteredName(reflect.TypeOf(spec))
+	assert.False(t, ok)
+}
+
+type Celsius struct {
+	Degrees float64
+}
+
+func TestRegister(t *testing.T) {
+	parse.Register("Temperature", func(s string) (Celsius, error) {
+		if !strings.HasSuffix(s, "C") {
+			return Celsius{}, fmt.Errorf("missing unit")
+		}
+
+		deg, err := strconv.ParseFloat(strings.TrimSuffix(s, "C"), 64)
+		return Celsius{Degrees: deg}, err
+	})
+
+	spec := &struct {
+		Temp  Celsius
+		Temps []*Celsius
+	}{}
+
+	s, err := structs.New(spec)
+	assert.Ok(t, err)
+
+	temp, _ := s.Field("Temp")
+	assert.Ok(t, parse.ParseField("21.5C", temp))
+	assert.Equals(t, Celsius{21.5}, spec.Temp)
+	assert.True(t, parse.IsDecodable(temp))
+
+	temps, _ := s.Field("Temps")
+	assert.Ok(t, parse.ParseField("-4C,100C", temps))
+	assert.Equals(t, []*Celsius{{-4}, {100}}, spec.Temps)
+
+	err = parse.ParseField("-4C,100F", temps)
+	target := &errors.ParseError{}
+	assert.True(t, goerrs.As(err, &target))
+	assert.Equals(t, "Temps[1]", target.Path)
+	assert.Equals(t, "Temperature", target.Type)
+}
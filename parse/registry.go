@@ -0,0 +1,168 @@
+package parse
+
+import (
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// parser is a registered parser for a type along with the name of the type in usage.
+type parser struct {
+	name  string
+	parse func(string) (reflect.Value, error)
+}
+
+var (
+	regmu    sync.RWMutex
+	registry = make(map[reflect.Type]*parser)
+)
+
+// Register a function that parses values of the type T, e.g. for third-party types
+// that cannot implement the Decoder interface. The name describes the type in usage.
+// Registered parsers take precedence over all other parsing methods for fields of the
+// type or of pointers to the type. Registering a type again replaces its parser.
+func Register[T any](name string, parse func(string) (T, error)) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	p := &parser{
+		name: name,
+		parse: func(value string) (reflect.Value, error) {
+			val, err := parse(value)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(&val).Elem(), nil
+		},
+	}
+
+	regmu.Lock()
+	registry[typ] = p
+	regmu.Unlock()
+}
+
+// RegisteredName returns the name of the type (or of the type it points to) if a parser
+// is registered for it.
+func RegisteredName(typ reflect.Type) (string, bool) {
+	if p, _ := lookup(typ); p != nil {
+		return p.name, true
+	}
+	return "", false
+}
+
+// Registered returns true if a parser is registered for exactly the type without
+// following pointers, e.g. so that pointers to registered types such as *time.Location
+// are not dereferenced as structs when gathering fields.
+func Registered(typ reflect.Type) bool {
+	regmu.RLock()
+	defer regmu.RUnlock()
+	_, ok := registry[typ]
+	return ok
+}
+
+// Returns true if a parser is registered for the type or for the type it points to.
+func isRegistered(typ reflect.Type) bool {
+	p, _ := lookup(typ)
+	return p != nil
+}
+
+// Returns the parser registered for the type or for the type it points to along with
+// the number of pointers to follow to get to the registered type.
+func lookup(typ reflect.Type) (_ *parser, depth int) {
+	regmu.RLock()
+	defer regmu.RUnlock()
+
+	for {
+		if p, ok := registry[typ]; ok {
+			return p, depth
+		}
+
+		if typ.Kind() != reflect.Ptr {
+			return nil, 0
+		}
+		typ = typ.Elem()
+		depth++
+	}
+}
+
+// Parses the value with the parser registered for the type of the field, allocating any
+// pointers to the registered type. Returns a nil parser if no parser is registered.
+func parseRegistered(value string, field reflect.Value) (_ *parser, err error) {
+	p, depth := lookup(field.Type())
+	if p == nil {
+		return nil, nil
+	}
+
+	var val reflect.Value
+	if val, err = p.parse(value); err != nil {
+		return p, err
+	}
+
+	for ; depth > 0; depth-- {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+
+	field.Set(val)
+	return p, nil
+}
+
+func init() {
+	Register("IP address", func(s string) (net.IP, error) {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "IP address", Text: s}
+		}
+		return ip, nil
+	})
+
+	Register("CIDR", func(s string) (net.IPNet, error) {
+		_, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return net.IPNet{}, err
+		}
+		return *ipnet, nil
+	})
+
+	Register("URL", func(s string) (url.URL, error) {
+		u, err := url.Parse(s)
+		if err != nil {
+			return url.URL{}, err
+		}
+		return *u, nil
+	})
+
+	Register("Regular expression", func(s string) (regexp.Regexp, error) {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return regexp.Regexp{}, err
+		}
+		return *re, nil
+	})
+
+	Register("Time zone", time.LoadLocation)
+	Register("IP address", netip.ParseAddr)
+	Register("IP address and port", netip.ParseAddrPort)
+
+	Register("Big integer", func(s string) (big.Int, error) {
+		n, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			return big.Int{}, &strconv.NumError{Func: "SetString", Num: s, Err: strconv.ErrSyntax}
+		}
+		return *n, nil
+	})
+
+	Register("Big float", func(s string) (big.Float, error) {
+		f, _, err := big.ParseFloat(s, 10, 0, big.ToNearestEven)
+		if err != nil {
+			return big.Float{}, err
+		}
+		return *f, nil
+	})
+}
@@ -0,0 +1,12 @@
+//go:build go1.21
+
+package parse
+
+import "log/slog"
+
+func init() {
+	Register("Log level", func(s string) (level slog.Level, err error) {
+		err = level.UnmarshalText([]byte(s))
+		return level, err
+	})
+}
@@ -0,0 +1,27 @@
+//go:build go1.21
+
+package parse_test
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"go.rtnl.ai/confire/assert"
+	"go.rtnl.ai/confire/parse"
+)
+
+func TestRegistrySlog(t *testing.T) {
+	var level slog.Level
+	assert.Ok(t, parse.Parse("warn", reflect.ValueOf(&level)))
+	assert.Equals(t, slog.LevelWarn, level)
+
+	assert.Ok(t, parse.Parse("debug+2", reflect.ValueOf(&level)))
+	assert.Equals(t, slog.LevelDebug+2, level)
+
+	assert.NotOk(t, parse.Parse("loud", reflect.ValueOf(&level)))
+
+	name, ok := parse.RegisteredName(reflect.TypeOf(level))
+	assert.True(t, ok)
+	assert.Equals(t, "Log level", name)
+}
@@ -0,0 +1,52 @@
+package parse
+
+import (
+	goerrs "errors"
+	"strconv"
+
+	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/structs"
+)
+
+const tagSecret = "secret"
+
+// Mask is rendered in place of the value of a secret field.
+const Mask = "******"
+
+// IsSecret returns true if the field is marked as secret by the secret struct tag.
+// The values of secret fields are masked anywhere that confire renders them.
+func IsSecret(field *structs.Field) bool {
+	secret, _ := strconv.ParseBool(field.Tag(tagSecret))
+	return secret
+}
+
+// RedactValue returns the mask in place of the value if the field is secret.
+func RedactValue(field *structs.Field, value string) string {
+	if value != "" && IsSecret(field) {
+		return Mask
+	}
+	return value
+}
+
+// RedactError masks the value of any parse error if the field is secret and wraps the
+// underlying error, whose message often contains the value, in a redacted error.
+func RedactError(field *structs.Field, err error) error {
+	if err == nil || !IsSecret(field) {
+		return err
+	}
+	return MaskError(err)
+}
+
+// MaskError masks the value of any parse error and wraps the underlying error in a
+// redacted error regardless of the field, e.g. when the value was interpolated from the
+// value of a secret field.
+func MaskError(err error) error {
+	target := &errors.ParseError{}
+	if goerrs.As(err, &target) {
+		target.Value = Mask
+		if _, ok := target.Err.(*errors.RedactedError); !ok && target.Err != nil {
+			target.Err = &errors.RedactedError{Err: target.Err}
+		}
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+package parse_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/confire/assert"
+	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/parse"
+	"go.rtnl.ai/confire/structs"
+)
+
+type SecretSpecification struct {
+	APIKey  int           `secret:"true"`
+	Token   time.Duration `secret:"true"`
+	Timeout time.Duration
+}
+
+func TestSecret(t *testing.T) {
+	s, err := structs.New(&SecretSpecification{})
+	assert.Ok(t, err)
+
+	apiKey, _ := s.Field("APIKey")
+	token, _ := s.Field("Token")
+	timeout, _ := s.Field("Timeout")
+
+	assert.True(t, parse.IsSecret(apiKey))
+	assert.False(t, parse.IsSecret(timeout))
+
+	assert.Equals(t, parse.Mask, parse.RedactValue(apiKey, "sk-supersecret"))
+	assert.Equals(t, "", parse.RedactValue(apiKey, ""))
+	assert.Equals(t, "1s", parse.RedactValue(timeout, "1s"))
+
+	t.Run("NumError", func(t *testing.T) {
+		err := parse.ParseField("sk-supersecret", apiKey)
+		assert.ErrorIs(t, err, strconv.ErrSyntax)
+		assert.False(t, strings.Contains(err.Error(), "sk-supersecret"))
+		assert.Equals(t, `confire: could not parse APIKey from : converting "******" to type int: invalid syntax`, err.Error())
+	})
+
+	t.Run("Redacted", func(t *testing.T) {
+		err := parse.ParseField("sk-supersecret", token)
+		assert.NotOk(t, err)
+		assert.Equals(t, parse.Mask, err.(*errors.ParseError).Value)
+		assert.False(t, strings.Contains(err.Error(), "sk-supersecret"))
+		assert.Equals(t, `confire: could not parse Token from : converting "******" to type Duration: secret value redacted`, err.Error())
+	})
+
+	t.Run("NotSecret", func(t *testing.T) {
+		err := parse.ParseField("sk-supersecret", timeout)
+		assert.NotOk(t, err)
+		assert.True(t, strings.Contains(err.Error(), "sk-supersecret"))
+	})
+}
@@ -0,0 +1,94 @@
+/*
+Package source defines the interface that is used to load configuration values into a
+specification from defaults, configuration files, the environment, or any other source
+of configuration. Sources are loaded in order so that values from later sources take
+precedence over values from earlier sources.
+*/
+package source
+
+import (
+	"context"
+	"fmt"
+)
+
+// Source loads configuration values into the specification, which must be a pointer to
+// a struct. Sources should only modify the fields that they have values for so that
+// the values loaded by previous sources are preserved. The gathered state is shared by
+// all of the sources that are loaded into the same specification.
+type Source interface {
+	Load(ctx context.Context, spec interface{}, gathered *Gathered) error
+}
+
+// Func is an adapter to allow the use of ordinary functions as configuration sources.
+type Func func(ctx context.Context, spec interface{}, gathered *Gathered) error
+
+// Load calls f(ctx, spec, gathered).
+func (f Func) Load(ctx context.Context, spec interface{}, gathered *Gathered) error {
+	return f(ctx, spec, gathered)
+}
+
+// Gathered contains the state that is shared between sources while a specification is
+// being loaded.
+type Gathered struct {
+	Prefix  string  // The prefix passed to confire.Process (e.g. for environment variables)
+	Origins Origins // The origin of each field value that was set by a source
+}
+
+// New creates the gathered state for loading a specification with the given prefix.
+func New(prefix string) *Gathered {
+	return &Gathered{Prefix: prefix, Origins: make(Origins)}
+}
+
+// Kind describes the type of source that set the value of a field.
+type Kind string
+
+const (
+	Default Kind = "default"
+	File    Kind = "file"
+	Env     Kind = "env"
+	Flag    Kind = "flag"
+)
+
+// Origin describes where the value of a field was loaded from.
+type Origin struct {
+	Kind     Kind   // The kind of source that set the value
+	Location string // The environment variable, file:line, or flag that set the value
+	Value    string // The raw string value that was parsed into the field (if a scalar)
+	Secret   bool   // The value was interpolated from a secret field and is masked
+}
+
+func (o Origin) String() string {
+	if o.Location == "" {
+		return fmt.Sprintf("%s %q", o.Kind, o.Value)
+	}
+	return fmt.Sprintf("%s %s=%q", o.Kind, o.Location, o.Value)
+}
+
+// Origins maps the dotted path of a field in the specification (e.g. Database.URL) to
+// the origin of its value. Fields of embedded structs are referred to by their promoted
+// name. Fields that were not set by any source are not included.
+type Origins map[string]Origin
+
+// Set the origin of the field at the specified path, replacing the origin of any value
+// set by a previous source. Set is a no-op if the gathered state or its origins are nil
+// so that sources do not have to check if origins are being tracked.
+func (g *Gathered) Set(path string, origin Origin) {
+	if g != nil && g.Origins != nil {
+		g.Origins[path] = origin
+	}
+}
+
+// Load each of the sources into the specification in order, stopping at the first
+// error or if the context is canceled.
+func Load(ctx context.Context, spec interface{}, gathered *Gathered, sources ...Source) (err error) {
+	for _, src := range sources {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		if err = src.Load(ctx, spec, gathered); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package source_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.rtnl.ai/confire/assert"
+	"go.rtnl.ai/confire/source"
+)
+
+type Specification struct {
+	Order []string
+}
+
+func appender(name string) source.Source {
+	return source.Func(func(_ context.Context, spec interface{}, g *source.Gathered) error {
+		s := spec.(*Specification)
+		s.Order = append(s.Order, g.Prefix+":"+name)
+		return nil
+	})
+}
+
+func TestLoad(t *testing.T) {
+	spec := &Specification{}
+	err := source.Load(context.Background(), spec, source.New("test"), appender("a"), appender("b"), appender("c"))
+	assert.Ok(t, err)
+	assert.Equals(t, []string{"test:a", "test:b", "test:c"}, spec.Order)
+}
+
+func TestLoadError(t *testing.T) {
+	failed := errors.New("source failed")
+	failure := source.Func(func(context.Context, interface{}, *source.Gathered) error {
+		return failed
+	})
+
+	spec := &Specification{}
+	err := source.Load(context.Background(), spec, source.New("test"), appender("a"), failure, appender("c"))
+	assert.ErrorIs(t, err, failed)
+	assert.Equals(t, []string{"test:a"}, spec.Order)
+}
+
+func TestLoadCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	spec := &Specification{}
+	err := source.Load(ctx, spec, source.New("test"), appender("a"))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equals(t, 0, len(spec.Order))
+}
+
+func TestOrigins(t *testing.T) {
+	gathered := source.New("test")
+	gathered.Set("Port", source.Origin{Kind: source.Default, Value: "8080"})
+	gathered.Set("Port", source.Origin{Kind: source.Env, Location: "TEST_PORT", Value: "8000"})
+
+	// Later sources replace the origin of earlier sources
+	assert.Equals(t, 1, len(gathered.Origins))
+	assert.Equals(t, source.Origin{Kind: source.Env, Location: "TEST_PORT", Value: "8000"}, gathered.Origins["Port"])
+	assert.Equals(t, `default "8080"`, source.Origin{Kind: source.Default, Value: "8080"}.String())
+
+	// Set is a no-op if origins are not being tracked
+	var nilGathered *source.Gathered
+	nilGathered.Set("Port", source.Origin{})
+	(&source.Gathered{}).Set("Port", source.Origin{})
+}
@@ -3,6 +3,7 @@ package structs
 import (
 	"fmt"
 	"reflect"
+	"sort"
 
 	"go.rtnl.ai/confire/errors"
 )
@@ -198,3 +199,112 @@ func (f *Field) InterfaceFrom(fn func(interface{}, *bool)) {
 func (f *Field) Fields() []*Field {
 	return getFields(f.value)
 }
+
+// IsStructSlice returns true if the field is a slice of structs or of pointers to
+// structs, e.g. a list of peers that each have their own configuration.
+func (f *Field) IsStructSlice() bool {
+	if f.value.Kind() != reflect.Slice {
+		return false
+	}
+
+	elem := f.value.Type().Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem.Kind() == reflect.Struct
+}
+
+// Len returns the length of the field's value. It panics if the field's Kind is not
+// Array, Chan, Map, Slice, or String.
+func (f *Field) Len() int {
+	return f.value.Len()
+}
+
+// Index returns a Field with the i'th element of the slice or array field. Pointers to
+// structs that are nil are initialized to point to a zero-valued version of the struct.
+// It panics if the field's Kind is not Array or Slice or if i is out of range.
+func (f *Field) Index(i int) *Field {
+	elem := &Field{
+		value: f.value.Index(i),
+		field: f.field,
+	}
+
+	if elem.Kind() == reflect.Ptr {
+		if err := elem.Init(); err != nil {
+			panic(err)
+		}
+	}
+	return elem
+}
+
+// Grow increases the length of a slice field to n, keeping any existing elements and
+// appending zero-valued elements. If the slice already has n elements it is unchanged.
+func (f *Field) Grow(n int) error {
+	if f.value.Kind() != reflect.Slice {
+		return fmt.Errorf("%w: cannot grow field type %q", errors.ErrNotSettable, f.value.Kind())
+	}
+
+	if n <= f.value.Len() {
+		return nil
+	}
+
+	if !f.IsExported() {
+		return errors.ErrNotExported
+	}
+
+	if !f.value.CanSet() {
+		return errors.ErrNotSettable
+	}
+
+	grown := reflect.MakeSlice(f.value.Type(), n, n)
+	reflect.Copy(grown, f.value)
+	f.value.Set(grown)
+	return nil
+}
+
+// MapKeys returns Fields with the keys of the map field sorted by their string
+// representation so that the keys are always visited in the same order. It panics if
+// the field's Kind is not Map.
+func (f *Field) MapKeys() []*Field {
+	keys := f.value.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+
+	fields := make([]*Field, 0, len(keys))
+	for _, key := range keys {
+		fields = append(fields, &Field{value: key, field: f.field})
+	}
+	return fields
+}
+
+// MapIndex returns a Field with the value of the map field for the key. Map values are
+// not addressable so the returned field cannot be set. It panics if the field's Kind is
+// not Map.
+func (f *Field) MapIndex(key *Field) *Field {
+	return &Field{
+		value: f.value.MapIndex(key.value),
+		field: f.field,
+	}
+}
+
+// Named returns a copy of the field with a different name, e.g. to identify an element
+// of a slice or map field as Peers[3] or Labels[env].
+func (f *Field) Named(name string) *Field {
+	field := f.field
+	field.Name = name
+	return &Field{
+		value: f.value,
+		field: field,
+	}
+}
+
+// Scratch returns a copy of the field that holds a new zero value of the field's type
+// rather than the field's value, e.g. to check that a value can be parsed into the field
+// using its tags without modifying the field.
+func (f *Field) Scratch() *Field {
+	return &Field{
+		value: reflect.New(f.value.Type()).Elem(),
+		field: f.field,
+	}
+}
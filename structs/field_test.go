@@ -76,3 +76,97 @@ func (w *WebColor) IVal() string {
 	// appease linter
 	return w.unexported
 }
+
+func TestStructSlice(t *testing.T) {
+	type Peer struct {
+		Host string
+	}
+
+	spec := &struct {
+		Peers    []Peer
+		Pointers []*Peer
+		Names    []string
+	}{Peers: []Peer{{Host: "alpha"}}}
+
+	s, err := structs.New(spec)
+	assert.Ok(t, err)
+
+	peers, err := s.Field("Peers")
+	assert.Ok(t, err)
+	assert.True(t, peers.IsStructSlice())
+	assert.Equals(t, 1, peers.Len())
+
+	// Growing the slice keeps the existing elements
+	assert.Ok(t, peers.Grow(3))
+	assert.Equals(t, []Peer{{Host: "alpha"}, {}, {}}, spec.Peers)
+
+	// The slice is never shrunk
+	assert.Ok(t, peers.Grow(1))
+	assert.Equals(t, 3, peers.Len())
+
+	elem := peers.Index(2)
+	assert.Equals(t, reflect.Struct, elem.Kind())
+	elem.Pointer().(*Peer).Host = "charlie"
+	assert.Equals(t, "charlie", spec.Peers[2].Host)
+
+	// Nil pointers to structs are initialized when indexed
+	pointers, err := s.Field("Pointers")
+	assert.Ok(t, err)
+	assert.True(t, pointers.IsStructSlice())
+	assert.Ok(t, pointers.Grow(2))
+	assert.True(t, spec.Pointers[1] == nil)
+	assert.Equals(t, reflect.Ptr, pointers.Index(1).Kind())
+	assert.Equals(t, &Peer{}, spec.Pointers[1])
+
+	names, err := s.Field("Names")
+	assert.Ok(t, err)
+	assert.False(t, names.IsStructSlice())
+}
+
+func TestMapElements(t *testing.T) {
+	spec := &struct {
+		Weights map[string]float64 `desc:"weights"`
+	}{Weights: map[string]float64{"charlie": 0.5, "alpha": 0.25, "bravo": 0.25}}
+
+	s, err := structs.New(spec)
+	assert.Ok(t, err)
+
+	weights, err := s.Field("Weights")
+	assert.Ok(t, err)
+
+	// Keys are sorted and the elements keep the tags of the field
+	keys := weights.MapKeys()
+	assert.Equals(t, 3, len(keys))
+	assert.Equals(t, "alpha", keys[0].Value())
+	assert.Equals(t, "charlie", keys[2].Value())
+	assert.Equals(t, "weights", keys[0].Tag("desc"))
+
+	value := weights.MapIndex(keys[2])
+	assert.Equals(t, 0.5, value.Value())
+	assert.False(t, value.CanSet())
+
+	// Naming an element does not rename the field
+	named := value.Named("Weights[charlie]")
+	assert.Equals(t, "Weights[charlie]", named.Name())
+	assert.Equals(t, "Weights", value.Name())
+	assert.Equals(t, 0.5, named.Value())
+	assert.True(t, named.IsExported())
+}
+
+func TestScratch(t *testing.T) {
+	spec := &struct {
+		Ports []int `sep:";"`
+	}{Ports: []int{80}}
+
+	s, err := structs.New(spec)
+	assert.Ok(t, err)
+
+	ports, err := s.Field("Ports")
+	assert.Ok(t, err)
+
+	scratch := ports.Scratch()
+	assert.Equals(t, ";", scratch.Tag("sep"))
+	assert.True(t, scratch.IsZero())
+	assert.Ok(t, scratch.Set([]int{443}))
+	assert.Equals(t, []int{80}, spec.Ports)
+}
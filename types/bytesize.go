@@ -0,0 +1,120 @@
+/*
+Package types provides configuration field types for values that are commonly specified
+in a human friendly format, such as byte sizes like 10MB and durations like 7d. All of
+the types implement the parse.Decoder interface so they can be used in specifications
+with environment variables, defaults, flags, and configuration files.
+*/
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ByteSize is a number of bytes that is parsed from a human readable size such as
+// 512KiB, 10MB, or 1.5G. Units are case-insensitive and may be separated from the number
+// by whitespace; units with an i (e.g. KiB, MiB) are powers of 1024 and units without an
+// i (e.g. K, KB, M, MB) are powers of 1000. A number without a unit is a number of bytes.
+// Fractional sizes are rounded to the nearest byte.
+type ByteSize uint64
+
+// Decimal (SI) byte size units.
+const (
+	Byte ByteSize = 1
+	KB            = 1000 * Byte
+	MB            = 1000 * KB
+	GB            = 1000 * MB
+	TB            = 1000 * GB
+	PB            = 1000 * TB
+	EB            = 1000 * PB
+)
+
+// Binary (IEC) byte size units.
+const (
+	KiB = 1024 * Byte
+	MiB = 1024 * KiB
+	GiB = 1024 * MiB
+	TiB = 1024 * GiB
+	PiB = 1024 * TiB
+	EiB = 1024 * PiB
+)
+
+// Byte size units from largest to smallest, used to render sizes.
+var byteUnits = []struct {
+	name string
+	size ByteSize
+}{
+	{"EiB", EiB}, {"EB", EB},
+	{"PiB", PiB}, {"PB", PB},
+	{"TiB", TiB}, {"TB", TB},
+	{"GiB", GiB}, {"GB", GB},
+	{"MiB", MiB}, {"MB", MB},
+	{"KiB", KiB}, {"KB", KB},
+}
+
+// Maps the lower case byte size units to their size.
+var byteSuffixes = map[string]ByteSize{
+	"":  Byte,
+	"b": Byte,
+	"k": KB, "kb": KB, "ki": KiB, "kib": KiB,
+	"m": MB, "mb": MB, "mi": MiB, "mib": MiB,
+	"g": GB, "gb": GB, "gi": GiB, "gib": GiB,
+	"t": TB, "tb": TB, "ti": TiB, "tib": TiB,
+	"p": PB, "pb": PB, "pi": PiB, "pib": PiB,
+	"e": EB, "eb": EB, "ei": EiB, "eib": EiB,
+}
+
+// ParseByteSize parses a human readable byte size such as 512KiB, 10MB, or 1.5G.
+func ParseByteSize(s string) (ByteSize, error) {
+	value := strings.TrimSpace(s)
+	idx := strings.IndexFunc(value, unicode.IsLetter)
+	if idx < 0 {
+		idx = len(value)
+	}
+
+	number := strings.TrimSpace(value[:idx])
+	unit, ok := byteSuffixes[strings.ToLower(value[idx:])]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q in byte size %q", value[idx:], s)
+	}
+
+	// Parse whole numbers directly so that large sizes do not lose precision.
+	if n, err := strconv.ParseUint(number, 10, 64); err == nil {
+		if n > math.MaxUint64/uint64(unit) {
+			return 0, fmt.Errorf("byte size %q is too large", s)
+		}
+		return ByteSize(n) * unit, nil
+	}
+
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	f = math.Round(f * float64(unit))
+	if f >= math.MaxUint64 {
+		return 0, fmt.Errorf("byte size %q is too large", s)
+	}
+	return ByteSize(f), nil
+}
+
+// Decode implements the parse.Decoder interface.
+func (b *ByteSize) Decode(value string) (err error) {
+	*b, err = ParseByteSize(value)
+	return err
+}
+
+// String renders the size in the largest unit that the size is a whole multiple of so
+// that the rendered size can be parsed back into the same number of bytes, e.g. 10MB
+// or 512KiB. Sizes that are not a multiple of a kilobyte are rendered in bytes.
+func (b ByteSize) String() string {
+	for _, unit := range byteUnits {
+		if b >= unit.size && b%unit.size == 0 {
+			return strconv.FormatUint(uint64(b/unit.size), 10) + unit.name
+		}
+	}
+	return strconv.FormatUint(uint64(b), 10) + "B"
+}
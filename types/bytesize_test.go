@@ -0,0 +1,79 @@
+package types_test
+
+import (
+	"testing"
+
+	"go.rtnl.ai/confire/assert"
+	. "go.rtnl.ai/confire/types"
+)
+
+func TestParseByteSize(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected ByteSize
+	}{
+		{"0", 0},
+		{"42", 42},
+		{"42B", 42},
+		{"512KiB", 512 * KiB},
+		{"512kib", 512 * KiB},
+		{"10MB", 10 * MB},
+		{"10 MB", 10 * MB},
+		{" 10mb ", 10 * MB},
+		{"1.5G", 1500 * MB},
+		{"1.5Gi", 1536 * MiB},
+		{"1.5GiB", 1536 * MiB},
+		{"2T", 2 * TB},
+		{"1PiB", PiB},
+		{"0.5KB", 500},
+		{"1.0001KiB", 1024},
+	}
+
+	for _, tc := range testCases {
+		actual, err := ParseByteSize(tc.in)
+		assert.Ok(t, err)
+		assert.Equals(t, tc.expected, actual)
+	}
+
+	for _, in := range []string{"", "MB", "-1KB", "10XB", "1.2.3MB", "16EiB", "20000000000000000000", "inf", "1e3KB"} {
+		_, err := ParseByteSize(in)
+		assert.NotOk(t, err)
+	}
+}
+
+func TestByteSizeDecode(t *testing.T) {
+	var b ByteSize
+	assert.Ok(t, b.Decode("64MiB"))
+	assert.Equals(t, 64*MiB, b)
+
+	assert.NotOk(t, b.Decode("64 megabytes"))
+}
+
+func TestByteSizeString(t *testing.T) {
+	testCases := []struct {
+		in       ByteSize
+		expected string
+	}{
+		{0, "0B"},
+		{1, "1B"},
+		{1023, "1023B"},
+		{1024, "1KiB"},
+		{1500, "1500B"},
+		{2000, "2KB"},
+		{512 * KiB, "512KiB"},
+		{10 * MB, "10MB"},
+		{1000 * KiB, "1000KiB"},
+		{GiB, "1GiB"},
+		{1536 * MiB, "1536MiB"},
+		{3 * EB, "3EB"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equals(t, tc.expected, tc.in.String())
+
+		// The rendered size can be parsed back into the same size
+		actual, err := ParseByteSize(tc.expected)
+		assert.Ok(t, err)
+		assert.Equals(t, tc.in, actual)
+	}
+}
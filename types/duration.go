@@ -0,0 +1,143 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Day and week durations that are not defined by the time package.
+const (
+	Day  = 24 * time.Hour
+	Week = 7 * Day
+)
+
+// Duration is a time.Duration that is parsed with time.ParseDuration but that also
+// accepts days (d) and weeks (w) as units, e.g. 7d, 2w, or 1d12h. Days are always 24
+// hours long; they do not account for daylight saving time.
+type Duration time.Duration
+
+// ParseDuration parses a duration string that may contain any of the units accepted by
+// time.ParseDuration along with days (d) and weeks (w).
+func ParseDuration(s string) (time.Duration, error) {
+	value := s
+	neg := false
+	if value != "" && (value[0] == '-' || value[0] == '+') {
+		neg = value[0] == '-'
+		value = value[1:]
+	}
+
+	if value == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	// Extract the day and week components and pass the rest to time.ParseDuration.
+	var (
+		long float64
+		rest strings.Builder
+	)
+
+	for value != "" {
+		i := strings.IndexFunc(value, func(r rune) bool { return r != '.' && (r < '0' || r > '9') })
+		if i < 0 {
+			i = len(value)
+		}
+
+		j := strings.IndexFunc(value[i:], func(r rune) bool { return r == '.' || (r >= '0' && r <= '9') })
+		if j < 0 {
+			j = len(value)
+		} else {
+			j += i
+		}
+
+		number, unit := value[:i], value[i:j]
+		if number == "" {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+
+		switch unit {
+		case "d", "w":
+			n, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+
+			if unit == "d" {
+				long += n * float64(Day)
+			} else {
+				long += n * float64(Week)
+			}
+		default:
+			rest.WriteString(number)
+			rest.WriteString(unit)
+		}
+		value = value[j:]
+	}
+
+	var d time.Duration
+	if rest.Len() > 0 {
+		var err error
+		if d, err = time.ParseDuration(rest.String()); err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+	}
+
+	if long+float64(d) > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid duration %q: out of range", s)
+	}
+
+	d += time.Duration(long)
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// Decode implements the parse.Decoder interface.
+func (d *Duration) Decode(value string) error {
+	duration, err := ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(duration)
+	return nil
+}
+
+// Duration returns the duration as a time.Duration.
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// String renders durations of a day or longer with days or weeks, e.g. 2w or 1d12h,
+// and drops zero minutes and seconds from the units of the time package, e.g. 1h30m
+// rather than 1h30m0s, so that the rendered duration can be parsed back.
+func (d Duration) String() string {
+	duration := time.Duration(d)
+	if duration < 0 && duration != math.MinInt64 {
+		return "-" + Duration(-duration).String()
+	}
+
+	if duration >= Week && duration%Week == 0 {
+		return strconv.FormatInt(int64(duration/Week), 10) + "w"
+	}
+
+	var days string
+	if duration >= Day {
+		days = strconv.FormatInt(int64(duration/Day), 10) + "d"
+		if duration %= Day; duration == 0 {
+			return days
+		}
+	}
+
+	s := duration.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return days + s
+}
@@ -0,0 +1,77 @@
+package types_test
+
+import (
+	"testing"
+	"time"
+
+	"go.rtnl.ai/confire/assert"
+	. "go.rtnl.ai/confire/types"
+)
+
+func TestParseDuration(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected time.Duration
+	}{
+		{"0", 0},
+		{"5s", 5 * time.Second},
+		{"1h30m", 90 * time.Minute},
+		{"7d", 7 * Day},
+		{"2w", 2 * Week},
+		{"1d12h", 36 * time.Hour},
+		{"1w2d3h4m", Week + 2*Day + 3*time.Hour + 4*time.Minute},
+		{"1.5d", 36 * time.Hour},
+		{"-2d", -2 * Day},
+		{"+3w", 3 * Week},
+		{"300ms", 300 * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		actual, err := ParseDuration(tc.in)
+		assert.Ok(t, err)
+		assert.Equals(t, tc.expected, actual)
+	}
+
+	for _, in := range []string{"", "-", "d", "5", "5x", "1.2.3d", "+-5s", "99999999w"} {
+		_, err := ParseDuration(in)
+		assert.NotOk(t, err)
+	}
+}
+
+func TestDurationDecode(t *testing.T) {
+	var d Duration
+	assert.Ok(t, d.Decode("2w"))
+	assert.Equals(t, 2*Week, d.Duration())
+
+	assert.NotOk(t, d.Decode("2 weeks"))
+	assert.Equals(t, 2*Week, d.Duration())
+}
+
+func TestDurationString(t *testing.T) {
+	testCases := []struct {
+		in       time.Duration
+		expected string
+	}{
+		{0, "0s"},
+		{5 * time.Second, "5s"},
+		{300 * time.Millisecond, "300ms"},
+		{90 * time.Minute, "1h30m"},
+		{2 * time.Hour, "2h"},
+		{time.Hour + time.Second, "1h0m1s"},
+		{Day, "1d"},
+		{7 * Day, "1w"},
+		{15 * Day, "15d"},
+		{36 * time.Hour, "1d12h"},
+		{2*Week + time.Minute, "14d1m"},
+		{-2 * Day, "-2d"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equals(t, tc.expected, Duration(tc.in).String())
+
+		// The rendered duration can be parsed back into the same duration
+		actual, err := ParseDuration(tc.expected)
+		assert.Ok(t, err)
+		assert.Equals(t, tc.in, actual)
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"go.rtnl.ai/confire/env"
 	"go.rtnl.ai/confire/parse"
+	"go.rtnl.ai/confire/types"
 )
 
 const (
@@ -51,15 +52,17 @@ func Usagef(prefix string, spec interface{}, out io.Writer, format string) error
 
 	// Specify the default usage template functions
 	functions := template.FuncMap{
-		"usage_key": func(v env.Info) string {
+		// Keys are not escaped so that map placeholders are rendered as <KEY>
+		"usage_key": func(v env.Info) template.HTML {
 			if v.Alt != "" {
-				return v.Alt
+				return template.HTML(v.Alt)
 			}
-			return v.Key
+			return template.HTML(v.Key)
 		},
 		"usage_description": func(v env.Info) string { return v.Field.Tag("desc") },
-		"usage_type":        func(v env.Info) string { return toTypeDescription(v.Field.Type()) },
-		"usage_default":     func(v env.Info) string { return v.Field.Tag("default") },
+		"usage_type":        func(v env.Info) string { return usageType(v) },
+		"usage_default":     func(v env.Info) string { return parse.RedactValue(v.Field, v.Field.Tag("default")) },
+		"usage_value":       func(v env.Info) string { return usageValue(v) },
 		"usage_required": func(v env.Info) (string, error) {
 			req := v.Field.Tag("required")
 			if req != "" {
@@ -94,11 +97,48 @@ func Usaget(prefix string, spec interface{}, out io.Writer, tmpl *template.Templ
 	return tmpl.Execute(out, infos)
 }
 
+// usageType describes the type of the field, which is JSON if the field is decoded from
+// JSON because it has the encoding:"json" struct tag.
+func usageType(v env.Info) string {
+	if parse.IsJSON(v.Field) {
+		typ := v.Field.Type()
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+
+		switch typ.Kind() {
+		case reflect.Array, reflect.Slice:
+			return "JSON array"
+		default:
+			return "JSON object"
+		}
+	}
+	return toTypeDescription(v.Field.Type())
+}
+
+// usageValue renders the current value of the field, masking the value of secrets.
+func usageValue(v env.Info) string {
+	val := v.Field.Reflect()
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return ""
+		}
+		val = val.Elem()
+	}
+
+	if !val.CanInterface() {
+		return ""
+	}
+	return parse.RedactValue(v.Field, fmt.Sprint(val.Interface()))
+}
+
 var (
 	decoderType           = reflect.TypeOf((*parse.Decoder)(nil)).Elem()
 	setterType            = reflect.TypeOf((*parse.Setter)(nil)).Elem()
 	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
 	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	byteSizeType          = reflect.TypeOf(types.ByteSize(0))
+	durationType          = reflect.TypeOf(types.Duration(0))
 )
 
 func implementsInterface(t reflect.Type) bool {
@@ -114,6 +154,17 @@ func implementsInterface(t reflect.Type) bool {
 
 // toTypeDescription converts Go types into a human readable description
 func toTypeDescription(t reflect.Type) string {
+	if name, ok := parse.RegisteredName(t); ok {
+		return name
+	}
+
+	switch t {
+	case byteSizeType:
+		return "Byte size (e.g. 10MB)"
+	case durationType:
+		return "Duration (e.g. 7d)"
+	}
+
 	switch t.Kind() {
 	case reflect.Array, reflect.Slice:
 		if t.Elem().Kind() == reflect.Uint8 {
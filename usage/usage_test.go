@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"net"
 	"net/url"
 	"os"
 	"strings"
@@ -13,6 +14,8 @@ import (
 	"time"
 
 	"go.rtnl.ai/confire/assert"
+	"go.rtnl.ai/confire/defaults"
+	"go.rtnl.ai/confire/types"
 	"go.rtnl.ai/confire/usage"
 )
 
@@ -79,6 +82,87 @@ func TestUsageCustomFormat(t *testing.T) {
 	compareUsage(t, "testdata/custom.txt", buf.String())
 }
 
+func TestUsageValue(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	s := struct {
+		Host   string
+		APIKey string `secret:"true" default:"sk-default"`
+		Token  string `secret:"true"`
+		Port   *int
+	}{Host: "localhost", APIKey: "sk-supersecret"}
+
+	err := usage.Usagef("confire", &s, buf, "{{range .}}{{usage_key .}}={{usage_value .}} ({{usage_default .}})\n{{end}}")
+	assert.Ok(t, err)
+	assert.Equals(t, "CONFIRE_HOST=localhost ()\nCONFIRE_APIKEY=****** (******)\nCONFIRE_TOKEN= ()\nCONFIRE_PORT= ()\n", buf.String())
+}
+
+func TestUsageMaps(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	type DBConfig struct {
+		Host string `desc:"database host"`
+		Port int    `default:"5432"`
+	}
+
+	s := struct {
+		Databases map[string]DBConfig
+	}{}
+
+	err := usage.Usagef("myapp", &s, buf, "{{range .}}{{usage_key .}} {{usage_type .}} ({{usage_default .}}) {{usage_description .}}\n{{end}}")
+	assert.Ok(t, err)
+	assert.Equals(t, "MYAPP_DATABASES_<KEY>_HOST String () database host\nMYAPP_DATABASES_<KEY>_PORT Integer (5432) \n", buf.String())
+}
+
+func TestUsageJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	type Endpoint struct {
+		Host string
+	}
+
+	s := struct {
+		Endpoint  Endpoint            `encoding:"json"`
+		Endpoints []Endpoint          `encoding:"json"`
+		Routes    map[string][]string `encoding:"json"`
+	}{}
+
+	err := usage.Usagef("myapp", &s, buf, "{{range .}}{{usage_key .}} {{usage_type .}}\n{{end}}")
+	assert.Ok(t, err)
+	assert.Equals(t, "MYAPP_ENDPOINT JSON object\nMYAPP_ENDPOINTS JSON array\nMYAPP_ROUTES JSON object\n", buf.String())
+}
+
+func TestUsageRegistered(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	s := struct {
+		Bind     net.IP
+		Networks []net.IPNet
+		Allowed  net.IPNet
+		Endpoint *url.URL
+		Zone     *time.Location
+	}{}
+
+	err := usage.Usagef("myapp", &s, buf, "{{range .}}{{usage_key .}} {{usage_type .}}\n{{end}}")
+	assert.Ok(t, err)
+	assert.Equals(t, "MYAPP_BIND IP address\nMYAPP_NETWORKS Comma-separated list of CIDR\nMYAPP_ALLOWED CIDR\nMYAPP_ENDPOINT URL\nMYAPP_ZONE Time zone\n", buf.String())
+}
+
+func TestUsageTypes(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	s := struct {
+		MaxSize   types.ByteSize `default:"10MB"`
+		Retention types.Duration `default:"2w"`
+		Sizes     []types.ByteSize
+	}{}
+	assert.Ok(t, defaults.Process(&s))
+
+	err := usage.Usagef("myapp", &s, buf, "{{range .}}{{usage_key .}} {{usage_type .}} {{usage_value .}}\n{{end}}")
+	assert.Ok(t, err)
+	assert.Equals(t, "MYAPP_MAXSIZE Byte size (e.g. 10MB) 10MB\nMYAPP_RETENTION Duration (e.g. 7d) 2w\nMYAPP_SIZES Comma-separated list of Byte size (e.g. 10MB) []\n", buf.String())
+}
+
 func TestUnknownKey(t *testing.T) {
 	buf := &bytes.Buffer{}
 	tabs := tabwriter.NewWriter(buf, 1, 0, 4, ' ', 0)
@@ -0,0 +1,567 @@
+package validate
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/parse"
+	"go.rtnl.ai/confire/structs"
+)
+
+// rule is a validator specified by the validate tag along with its parameter.
+type rule struct {
+	name  string
+	param string
+}
+
+// Parses the comma-separated validators of the validate tag, e.g. min=1,max=65535.
+// Commas in parameters must be escaped with a backslash, e.g. regexp=^a{1\,3}$ (note
+// that the backslash itself must be escaped in a struct tag).
+func parseRules(tag string) (rules []rule) {
+	var current strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			name, param, _ := strings.Cut(s, "=")
+			rules = append(rules, rule{name: strings.TrimSpace(name), param: param})
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			current.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			flush()
+		default:
+			current.WriteByte(tag[i])
+		}
+	}
+
+	flush()
+	return rules
+}
+
+// tagged applies a validator from the validate tag to a field.
+type tagged struct {
+	field *structs.Field
+	fn    Func
+	param string
+}
+
+func (t tagged) Validate() error {
+	return t.fn(t.field, t.param)
+}
+
+func validateRequired(field *structs.Field, _ string) error {
+	return Required(field).Validate()
+}
+
+// Strings, slices, arrays, and maps must have a length of at least the parameter;
+// numbers must be at least the parameter parsed as the type of the field, e.g. min=5s
+// for a time.Duration.
+func validateMin(field *structs.Field, param string) error {
+	return limit(field, "min", "at least", param, func(cmp int) bool { return cmp >= 0 })
+}
+
+// Strings, slices, arrays, and maps must have a length of at most the parameter;
+// numbers must be at most the parameter parsed as the type of the field.
+func validateMax(field *structs.Field, param string) error {
+	return limit(field, "max", "at most", param, func(cmp int) bool { return cmp <= 0 })
+}
+
+// Strings, slices, arrays, and maps must have a length of exactly the parameter.
+func validateLen(field *structs.Field, param string) error {
+	val, ok := indirect(field)
+	if !ok {
+		return nil
+	}
+
+	n, ok := length(val)
+	if !ok {
+		return unsupported("len", val.Type())
+	}
+
+	expected, err := strconv.Atoi(param)
+	if err != nil {
+		return invalidParam("len", param)
+	}
+
+	if n != expected {
+		return errors.Invalid("", field.Name(), "%s", lengthIssue(val, "exactly", expected))
+	}
+	return nil
+}
+
+// The value must be one of the space-separated values of the parameter.
+func validateOneOf(field *structs.Field, param string) error {
+	val, ok := indirect(field)
+	if !ok {
+		return nil
+	}
+
+	options := strings.Fields(param)
+	for _, option := range options {
+		eq, err := equals(val, option)
+		if err != nil {
+			return invalidParam("oneof", param)
+		}
+
+		if eq {
+			return nil
+		}
+	}
+
+	return errors.Invalid("", field.Name(), "must be one of %s", strings.Join(options, ", "))
+}
+
+func validateRegexp(field *structs.Field, param string) error {
+	return format(field, "regexp", func(s string) (string, error) {
+		re, err := compileRegexp(param)
+		if err != nil {
+			return "", invalidParam("regexp", param)
+		}
+
+		if !re.MatchString(s) {
+			return fmt.Sprintf("must match the regular expression %q", param), nil
+		}
+		return "", nil
+	})
+}
+
+func validateURL(field *structs.Field, _ string) error {
+	return format(field, "url", func(s string) (string, error) {
+		if u, err := url.Parse(s); err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be a valid URL with a scheme and host, e.g. https://example.com", nil
+		}
+		return "", nil
+	})
+}
+
+func validateHostname(field *structs.Field, _ string) error {
+	return format(field, "hostname", func(s string) (string, error) {
+		if !isHostname(s) {
+			return "must be a valid hostname, e.g. example.com", nil
+		}
+		return "", nil
+	})
+}
+
+// The value must be a host and port, e.g. localhost:8080; the host may be a hostname,
+// an IP address, or empty (e.g. :8080 to bind to all interfaces).
+func validateHostPort(field *structs.Field, _ string) error {
+	return format(field, "hostport", func(s string) (string, error) {
+		host, port, err := net.SplitHostPort(s)
+		if err == nil {
+			if _, err = strconv.ParseUint(port, 10, 16); err == nil {
+				if host == "" || net.ParseIP(host) != nil || isHostname(host) {
+					return "", nil
+				}
+			}
+		}
+		return "must be a valid host and port, e.g. localhost:8080", nil
+	})
+}
+
+func validateIP(field *structs.Field, _ string) error {
+	return format(field, "ip", func(s string) (string, error) {
+		if net.ParseIP(s) == nil {
+			return "must be a valid IP address", nil
+		}
+		return "", nil
+	})
+}
+
+func validateCIDR(field *structs.Field, _ string) error {
+	return format(field, "cidr", func(s string) (string, error) {
+		if _, _, err := net.ParseCIDR(s); err != nil {
+			return "must be a valid CIDR network, e.g. 10.0.0.0/8", nil
+		}
+		return "", nil
+	})
+}
+
+func validateEmail(field *structs.Field, _ string) error {
+	return format(field, "email", func(s string) (string, error) {
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			return "must be a valid email address", nil
+		}
+		return "", nil
+	})
+}
+
+func validateFile(field *structs.Field, _ string) error {
+	return format(field, "file", func(s string) (string, error) {
+		if info, err := os.Stat(s); err != nil || !info.Mode().IsRegular() {
+			return "must be the path to an existing file", nil
+		}
+		return "", nil
+	})
+}
+
+func validateDir(field *structs.Field, _ string) error {
+	return format(field, "dir", func(s string) (string, error) {
+		if info, err := os.Stat(s); err != nil || !info.IsDir() {
+			return "must be the path to an existing directory", nil
+		}
+		return "", nil
+	})
+}
+
+func validateAbsDir(field *structs.Field, _ string) error {
+	return format(field, "absdir", func(s string) (string, error) {
+		if !filepath.IsAbs(s) {
+			return "must be an absolute path to an existing directory", nil
+		}
+
+		if info, err := os.Stat(s); err != nil || !info.IsDir() {
+			return "must be an absolute path to an existing directory", nil
+		}
+		return "", nil
+	})
+}
+
+// Strings must contain non-whitespace characters, slices, arrays, and maps must have
+// at least one element, and any other value must not be zero valued.
+func validateNonEmpty(field *structs.Field, _ string) error {
+	val, ok := indirect(field)
+	switch {
+	case !ok:
+	case val.Kind() == reflect.String:
+		ok = strings.TrimSpace(val.String()) != ""
+	case val.Kind() == reflect.Slice || val.Kind() == reflect.Array || val.Kind() == reflect.Map:
+		ok = val.Len() > 0
+	default:
+		ok = !val.IsZero()
+	}
+
+	if !ok {
+		return errors.Invalid("", field.Name(), "must not be empty")
+	}
+	return nil
+}
+
+// The elements of slices and arrays or the values of maps must not contain duplicates.
+func validateUnique(field *structs.Field, _ string) error {
+	val, ok := indirect(field)
+	if !ok {
+		return nil
+	}
+
+	var values []reflect.Value
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			values = append(values, val.Index(i))
+		}
+	case reflect.Map:
+		iter := val.MapRange()
+		for iter.Next() {
+			values = append(values, iter.Value())
+		}
+	default:
+		return unsupported("unique", val.Type())
+	}
+
+	seen := make(map[interface{}]struct{}, len(values))
+	for _, value := range values {
+		var key interface{} = fmt.Sprintf("%#v", value.Interface())
+		if value.Type().Comparable() {
+			key = value.Interface()
+		}
+
+		if _, ok := seen[key]; ok {
+			// The repeated value is masked if the field is secret
+			repeated := parse.RedactValue(field, fmt.Sprint(value.Interface()))
+			return errors.Invalid("", field.Name(), "must only contain unique values but %s is repeated", repeated)
+		}
+		seen[key] = struct{}{}
+	}
+	return nil
+}
+
+// Validates that the length or the numeric value of the field is within the limit
+// specified by the parameter, where ok reports if the comparison to the limit is valid.
+func limit(field *structs.Field, name, qualifier, param string, ok func(cmp int) bool) error {
+	val, set := indirect(field)
+	if !set {
+		return nil
+	}
+
+	if n, isLen := length(val); isLen {
+		expected, err := strconv.Atoi(param)
+		if err != nil {
+			return invalidParam(name, param)
+		}
+
+		if !ok(compareInts(int64(n), int64(expected))) {
+			return errors.Invalid("", field.Name(), "%s", lengthIssue(val, qualifier, expected))
+		}
+		return nil
+	}
+
+	expected := reflect.New(val.Type()).Elem()
+	if err := parse.Parse(param, expected); err != nil {
+		return invalidParam(name, param)
+	}
+
+	var cmp int
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		cmp = compareInts(val.Int(), expected.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		a, b := val.Uint(), expected.Uint()
+		switch {
+		case a < b:
+			cmp = -1
+		case a > b:
+			cmp = 1
+		}
+	case reflect.Float32, reflect.Float64:
+		a, b := val.Float(), expected.Float()
+		switch {
+		case a < b:
+			cmp = -1
+		case a > b:
+			cmp = 1
+		}
+	default:
+		return unsupported(name, val.Type())
+	}
+
+	if !ok(cmp) {
+		return errors.Invalid("", field.Name(), "must be %s %s", qualifier, param)
+	}
+	return nil
+}
+
+// Applies a validator to the string value of the field, where check returns the issue
+// if the string is invalid. Empty strings are not validated; use required to ensure
+// that the field is set.
+func format(field *structs.Field, name string, check func(string) (string, error)) error {
+	val, ok := indirect(field)
+	if !ok {
+		return nil
+	}
+
+	if val.Kind() != reflect.String {
+		return unsupported(name, val.Type())
+	}
+
+	if val.String() == "" {
+		return nil
+	}
+
+	issue, err := check(val.String())
+	if err != nil {
+		return err
+	}
+
+	if issue != "" {
+		return errors.Invalid("", field.Name(), "%s", issue)
+	}
+	return nil
+}
+
+// Returns the value of the field, following any pointers, and false if it is nil.
+func indirect(field *structs.Field) (reflect.Value, bool) {
+	val := field.Reflect()
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return val, false
+		}
+		val = val.Elem()
+	}
+	return val, true
+}
+
+// Returns true if the value is equal to the string parsed as the type of the value.
+func equals(val reflect.Value, s string) (bool, error) {
+	if val.Kind() == reflect.String {
+		return val.String() == s, nil
+	}
+
+	expected := reflect.New(val.Type()).Elem()
+	if err := parse.Parse(s, expected); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(val.Interface(), expected.Interface()), nil
+}
+
+// Returns the number of characters in strings and the number of elements in slices,
+// arrays and maps, or false if the value does not have a length.
+func length(val reflect.Value) (int, bool) {
+	switch val.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(val.String()), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return val.Len(), true
+	default:
+		return 0, false
+	}
+}
+
+func lengthIssue(val reflect.Value, qualifier string, n int) string {
+	if val.Kind() == reflect.String {
+		return fmt.Sprintf("must be %s %d characters long", qualifier, n)
+	}
+	return fmt.Sprintf("must have %s %d elements", qualifier, n)
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// RFC 1123 hostnames: dot separated labels of letters, digits, and hyphens.
+var hostname = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*\.?$`)
+
+func isHostname(s string) bool {
+	return len(s) <= 253 && hostname.MatchString(s)
+}
+
+// Compiled regular expressions of the regexp validator by their pattern so that each
+// pattern is only compiled once, when the validators of the specification are gathered.
+var patterns sync.Map
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+// Checks of the parameters of the built-in validators and of the types of the fields
+// they are applied to, which are run when the validators are gathered so that mistakes
+// in the validate tag are reported as errors in the specification rather than as
+// invalid configuration values.
+var checks = map[string]func(typ reflect.Type, param string) error{
+	"min":      checkLimit("min"),
+	"max":      checkLimit("max"),
+	"len":      checkLen,
+	"oneof":    checkOneOf,
+	"regexp":   checkRegexp,
+	"url":      checkString("url"),
+	"hostname": checkString("hostname"),
+	"hostport": checkString("hostport"),
+	"ip":       checkString("ip"),
+	"cidr":     checkString("cidr"),
+	"email":    checkString("email"),
+	"file":     checkString("file"),
+	"dir":      checkString("dir"),
+	"absdir":   checkString("absdir"),
+	"unique":   checkUnique,
+}
+
+// The parameter of min and max is a length for strings, slices, arrays, and maps and is
+// parsed as the type of the field for numbers.
+func checkLimit(name string) func(reflect.Type, string) error {
+	return func(typ reflect.Type, param string) error {
+		switch typ.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+			if _, err := strconv.Atoi(param); err != nil {
+				return invalidParam(name, param)
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64:
+			if err := parse.Parse(param, reflect.New(typ).Elem()); err != nil {
+				return invalidParam(name, param)
+			}
+		default:
+			return unsupported(name, typ)
+		}
+		return nil
+	}
+}
+
+func checkLen(typ reflect.Type, param string) error {
+	switch typ.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		if _, err := strconv.Atoi(param); err != nil {
+			return invalidParam("len", param)
+		}
+		return nil
+	default:
+		return unsupported("len", typ)
+	}
+}
+
+func checkOneOf(typ reflect.Type, param string) error {
+	options := strings.Fields(param)
+	if len(options) == 0 {
+		return invalidParam("oneof", param)
+	}
+
+	for _, option := range options {
+		if _, err := equals(reflect.New(typ).Elem(), option); err != nil {
+			return invalidParam("oneof", param)
+		}
+	}
+	return nil
+}
+
+func checkRegexp(typ reflect.Type, param string) error {
+	if typ.Kind() != reflect.String {
+		return unsupported("regexp", typ)
+	}
+
+	if _, err := compileRegexp(param); err != nil {
+		return invalidParam("regexp", param)
+	}
+	return nil
+}
+
+func checkString(name string) func(reflect.Type, string) error {
+	return func(typ reflect.Type, _ string) error {
+		if typ.Kind() != reflect.String {
+			return unsupported(name, typ)
+		}
+		return nil
+	}
+}
+
+func checkUnique(typ reflect.Type, _ string) error {
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return nil
+	default:
+		return unsupported("unique", typ)
+	}
+}
+
+// Errors in the specification rather than in the value of the field are returned as
+// plain errors that complete the sentence "<field> ...", e.g. Port has an invalid min
+// parameter "one".
+func invalidParam(name, param string) error {
+	return fmt.Errorf("has an invalid %s parameter %q", name, param)
+}
+
+func unsupported(name string, typ reflect.Type) error {
+	return fmt.Errorf("cannot be validated with %s: unsupported type %s", name, typ)
+}
@@ -0,0 +1,239 @@
+package validate_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/confire/assert"
+	confireErrors "go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/types"
+	"go.rtnl.ai/confire/validate"
+)
+
+func TestBuiltinValidators(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	assert.Ok(t, os.WriteFile(path, []byte("debug: true\n"), 0600))
+
+	testCases := []struct {
+		name  string
+		spec  interface{}
+		issue string
+	}{
+		{"min", &struct {
+			Port int `validate:"min=1,max=65535"`
+		}{0}, "Port must be at least 1"},
+		{"max", &struct {
+			Port int `validate:"min=1,max=65535"`
+		}{70000}, "Port must be at most 65535"},
+		{"min uint", &struct {
+			Workers uint8 `validate:"min=2"`
+		}{1}, "Workers must be at least 2"},
+		{"max float", &struct {
+			Ratio float64 `validate:"max=0.5"`
+		}{0.75}, "Ratio must be at most 0.5"},
+		{"min duration", &struct {
+			Timeout time.Duration `validate:"min=1s"`
+		}{500 * time.Millisecond}, "Timeout must be at least 1s"},
+		{"max byte size", &struct {
+			MaxSize types.ByteSize `validate:"max=1GiB"`
+		}{2 * types.GiB}, "MaxSize must be at most 1GiB"},
+		{"min string", &struct {
+			Name string `validate:"min=3"`
+		}{"ab"}, "Name must be at least 3 characters long"},
+		{"max slice", &struct {
+			Peers []string `validate:"max=2"`
+		}{[]string{"a", "b", "c"}}, "Peers must have at most 2 elements"},
+		{"len", &struct {
+			Key string `validate:"len=4"`
+		}{"abcde"}, "Key must be exactly 4 characters long"},
+		{"oneof", &struct {
+			Level string `validate:"oneof=debug info warn error"`
+		}{"trace"}, "Level must be one of debug, info, warn, error"},
+		{"oneof int", &struct {
+			Replicas int `validate:"oneof=1 3 5"`
+		}{2}, "Replicas must be one of 1, 3, 5"},
+		{"regexp", &struct {
+			Code string `validate:"regexp=^[a-z]{2\\,3}$"`
+		}{"abcd"}, "Code must match the regular expression \"^[a-z]{2,3}$\""},
+		{"url", &struct {
+			Endpoint string `validate:"url"`
+		}{"example.com/api"}, "Endpoint must be a valid URL with a scheme and host, e.g. https://example.com"},
+		{"hostname", &struct {
+			Host string `validate:"hostname"`
+		}{"-bad-.example.com"}, "Host must be a valid hostname, e.g. example.com"},
+		{"hostport", &struct {
+			Bind string `validate:"hostport"`
+		}{"localhost"}, "Bind must be a valid host and port, e.g. localhost:8080"},
+		{"hostport port", &struct {
+			Bind string `validate:"hostport"`
+		}{"localhost:99999"}, "Bind must be a valid host and port, e.g. localhost:8080"},
+		{"ip", &struct {
+			Addr string `validate:"ip"`
+		}{"10.0.0.256"}, "Addr must be a valid IP address"},
+		{"cidr", &struct {
+			Network string `validate:"cidr"`
+		}{"10.0.0.0"}, "Network must be a valid CIDR network, e.g. 10.0.0.0/8"},
+		{"email", &struct {
+			Admin string `validate:"email"`
+		}{"Admin <admin@example.com>"}, "Admin must be a valid email address"},
+		{"file", &struct {
+			Path string `validate:"file"`
+		}{dir}, "Path must be the path to an existing file"},
+		{"dir", &struct {
+			Path string `validate:"dir"`
+		}{path}, "Path must be the path to an existing directory"},
+		{"absdir", &struct {
+			Path string `validate:"absdir"`
+		}{"testdata"}, "Path must be an absolute path to an existing directory"},
+		{"nonempty", &struct {
+			Name string `validate:"nonempty"`
+		}{"  "}, "Name must not be empty"},
+		{"nonempty slice", &struct {
+			Peers []string `validate:"nonempty"`
+		}{[]string{}}, "Peers must not be empty"},
+		{"unique", &struct {
+			Peers []string `validate:"unique"`
+		}{[]string{"a", "b", "a"}}, "Peers must only contain unique values but a is repeated"},
+		{"unique map", &struct {
+			Ports map[string]int `validate:"unique"`
+		}{map[string]int{"http": 80, "web": 80}}, "Ports must only contain unique values but 80 is repeated"},
+		{"unique secret", &struct {
+			Tokens []string `validate:"unique" secret:"true"`
+		}{[]string{"sk-alpha", "sk-alpha"}}, "Tokens must only contain unique values but ****** is repeated"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validate.Validate(tc.spec)
+			assert.NotOk(t, err)
+
+			var target *confireErrors.InvalidConfig
+			assert.True(t, errors.As(err, &target))
+			assert.Equals(t, "invalid configuration: "+tc.issue, err.Error())
+		})
+	}
+}
+
+func TestBuiltinValidatorsValid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	assert.Ok(t, os.WriteFile(path, []byte("debug: true\n"), 0600))
+
+	type Specification struct {
+		Port     int                `validate:"min=1,max=65535"`
+		Timeout  time.Duration      `validate:"min=1s,max=1m"`
+		MaxSize  types.ByteSize     `validate:"max=1GiB"`
+		Name     string             `validate:"min=3,max=8"`
+		Key      string             `validate:"len=4"`
+		Level    string             `validate:"oneof=debug info warn error"`
+		Replicas int                `validate:"oneof=1 3 5"`
+		Code     string             `validate:"regexp=^[a-z]{2\\,3}$"`
+		Endpoint string             `validate:"url"`
+		Host     string             `validate:"hostname"`
+		Bind     string             `validate:"hostport"`
+		Addr     string             `validate:"ip"`
+		Network  string             `validate:"cidr"`
+		Admin    string             `validate:"email"`
+		File     string             `validate:"file"`
+		Dir      string             `validate:"dir"`
+		AbsDir   string             `validate:"absdir"`
+		Peers    []string           `validate:"nonempty,unique,max=3"`
+		Ports    map[string]int     `validate:"unique"`
+		Optional *string            `validate:"url"`
+		Labels   map[string]float64 `validate:"len=0"`
+	}
+
+	valid := &Specification{
+		Port:     8080,
+		Timeout:  30 * time.Second,
+		MaxSize:  512 * types.MiB,
+		Name:     "confire",
+		Key:      "abcd",
+		Level:    "info",
+		Replicas: 3,
+		Code:     "abc",
+		Endpoint: "https://example.com/api",
+		Host:     "db.example.com",
+		Bind:     ":8080",
+		Addr:     "::1",
+		Network:  "10.0.0.0/8",
+		Admin:    "admin@example.com",
+		File:     path,
+		Dir:      dir,
+		AbsDir:   dir,
+		Peers:    []string{"a", "b"},
+		Ports:    map[string]int{"http": 80, "https": 443},
+	}
+	assert.Ok(t, validate.Validate(valid))
+
+	// Format validators do not apply to empty strings; use required to require a value
+	type Optional struct {
+		Endpoint string `validate:"url"`
+		Bind     string `validate:"required,hostport"`
+	}
+
+	err := validate.Validate(&Optional{})
+	assert.ErrorIs(t, err, confireErrors.ErrMissingRequired)
+	assert.Equals(t, "invalid configuration: Bind is required but not set", err.Error())
+}
+
+func TestBuiltinValidatorErrors(t *testing.T) {
+	// Mistakes in the validate tag are errors in the specification that are returned
+	// when the validators are gathered rather than invalid configuration values.
+	testCases := []struct {
+		spec interface{}
+		err  string
+	}{
+		{&struct {
+			Port int `validate:"min=one"`
+		}{Port: 8080}, "Port has an invalid min parameter \"one\""},
+		{&struct {
+			Port int `validate:"max=abc"`
+		}{}, "Port has an invalid max parameter \"abc\""},
+		{&struct {
+			Name string `validate:"len=five"`
+		}{}, "Name has an invalid len parameter \"five\""},
+		{&struct {
+			Port int `validate:"oneof=80 http"`
+		}{}, "Port has an invalid oneof parameter \"80 http\""},
+		{&struct {
+			Ports []int `validate:"url"`
+		}{Ports: []int{80}}, "Ports cannot be validated with url: unsupported type []int"},
+		{&struct {
+			Port int `validate:"regexp=^[0-9]+$"`
+		}{}, "Port cannot be validated with regexp: unsupported type int"},
+		{&struct {
+			Name string `validate:"regexp=("`
+		}{}, "Name has an invalid regexp parameter \"(\""},
+		{&struct {
+			Enabled bool `validate:"min=1"`
+		}{}, "Enabled cannot be validated with min: unsupported type bool"},
+		{&struct {
+			Port int `validate:"unique"`
+		}{}, "Port cannot be validated with unique: unsupported type int"},
+		{&struct {
+			Server struct {
+				Hosts []string `validate:"dive,min=x"`
+			}
+		}{}, "Server.Hosts has an invalid min parameter \"x\""},
+		{&struct {
+			Port int `validate:"min=1,positive"`
+		}{}, "unknown validator \"positive\""},
+	}
+
+	for _, tc := range testCases {
+		_, err := validate.Gather(tc.spec)
+		assert.NotOk(t, err)
+		assert.Equals(t, tc.err, err.Error())
+
+		err = validate.Validate(tc.spec)
+		assert.Equals(t, tc.err, err.Error())
+
+		target := &confireErrors.InvalidConfig{}
+		assert.False(t, errors.As(err, &target))
+	}
+}
@@ -0,0 +1,261 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/structs"
+)
+
+// crossFunc is a validator that depends on the values of other fields of the
+// specification, which are resolved by the scope of the field.
+type crossFunc func(field *structs.Field, param string, s scope) error
+
+// Cross-field validators that can be specified by name in the validate tag. Their
+// parameters are space-separated field paths (followed by values for required_if and
+// required_unless), e.g. required_if=TLS.Enabled true or required_with=CertFile KeyFile.
+var crossField = map[string]crossFunc{
+	"required_if":        validateRequiredIf,
+	"required_unless":    validateRequiredUnless,
+	"required_with":      validateRequiredWith,
+	"required_without":   validateRequiredWithout,
+	"excluded_with":      validateExcludedWith,
+	"mutually_exclusive": validateMutuallyExclusive,
+}
+
+// scope resolves the fields referenced by cross-field validators. Paths are resolved
+// relative to the struct that contains the field first (so that siblings can be
+// referenced by name) and then relative to the root specification being validated.
+type scope struct {
+	root   reflect.Value
+	parent reflect.Value
+}
+
+func (s scope) resolve(path string) (reflect.Value, error) {
+	if val, ok := fieldByPath(s.parent, path); ok {
+		return val, nil
+	}
+
+	if val, ok := fieldByPath(s.root, path); ok {
+		return val, nil
+	}
+	return reflect.Value{}, fmt.Errorf("references unknown field %q", path)
+}
+
+// Checks that the parameter of the cross-field validator is valid and that the fields it
+// references can be resolved, so that mistakes in the validate tag are reported when the
+// validators are gathered rather than as invalid configuration values.
+func (s scope) check(name, param string) (err error) {
+	switch name {
+	case "required_if", "required_unless":
+		_, _, err = matches(name, param, s)
+		return err
+	}
+
+	var paths []string
+	if paths, err = fieldPaths(name, param); err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if _, err = s.resolve(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns true if the field at the path has a non-zero value.
+func (s scope) isSet(path string) (bool, error) {
+	val, err := s.resolve(path)
+	if err != nil {
+		return false, err
+	}
+	return !val.IsZero(), nil
+}
+
+// The field is required if all of the fields have the specified values, e.g.
+// required_if=TLS.Enabled true or required_if=Mode cluster Replicas 3.
+func validateRequiredIf(field *structs.Field, param string, s scope) error {
+	match, conditions, err := matches("required_if", param, s)
+	if err != nil {
+		return err
+	}
+
+	if match && field.IsZero() {
+		return errors.Wrap("", field.Name(), "is required when %s", errors.ErrMissingRequired, conditions)
+	}
+	return nil
+}
+
+// The field is required unless all of the fields have the specified values, e.g.
+// required_unless=Mode dev.
+func validateRequiredUnless(field *structs.Field, param string, s scope) error {
+	match, conditions, err := matches("required_unless", param, s)
+	if err != nil {
+		return err
+	}
+
+	if !match && field.IsZero() {
+		return errors.Wrap("", field.Name(), "is required unless %s", errors.ErrMissingRequired, conditions)
+	}
+	return nil
+}
+
+// The field is required if any of the fields are set.
+func validateRequiredWith(field *structs.Field, param string, s scope) error {
+	paths, err := fieldPaths("required_with", param)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		var set bool
+		if set, err = s.isSet(path); err != nil {
+			return err
+		}
+
+		if set && field.IsZero() {
+			return errors.Wrap("", field.Name(), "is required when %s is set", errors.ErrMissingRequired, path)
+		}
+	}
+	return nil
+}
+
+// The field is required if any of the fields are not set.
+func validateRequiredWithout(field *structs.Field, param string, s scope) error {
+	paths, err := fieldPaths("required_without", param)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		var set bool
+		if set, err = s.isSet(path); err != nil {
+			return err
+		}
+
+		if !set && field.IsZero() {
+			return errors.Wrap("", field.Name(), "is required when %s is not set", errors.ErrMissingRequired, path)
+		}
+	}
+	return nil
+}
+
+// The field must not be set if any of the fields are set.
+func validateExcludedWith(field *structs.Field, param string, s scope) error {
+	paths, err := fieldPaths("excluded_with", param)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		var set bool
+		if set, err = s.isSet(path); err != nil {
+			return err
+		}
+
+		if set && !field.IsZero() {
+			return errors.Invalid("", field.Name(), "must not be set when %s is set", path)
+		}
+	}
+	return nil
+}
+
+// At most one of the field and the other fields can be set; combine with
+// required_without to require exactly one of the fields.
+func validateMutuallyExclusive(field *structs.Field, param string, s scope) error {
+	paths, err := fieldPaths("mutually_exclusive", param)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	if !field.IsZero() {
+		count++
+	}
+
+	for _, path := range paths {
+		var set bool
+		if set, err = s.isSet(path); err != nil {
+			return err
+		}
+
+		if set {
+			count++
+		}
+	}
+
+	if count > 1 {
+		return errors.Invalid("", field.Name(), "cannot be set with %s (only one of them may be set)", strings.Join(paths, " or "))
+	}
+	return nil
+}
+
+// Returns true if all of the fields in the field/value pairs of the parameter have the
+// specified value along with a description of the conditions for the issue.
+func matches(name, param string, s scope) (match bool, conditions string, err error) {
+	pairs := strings.Fields(param)
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return false, "", invalidParam(name, param)
+	}
+
+	match = true
+	descs := make([]string, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		var val reflect.Value
+		if val, err = s.resolve(pairs[i]); err != nil {
+			return false, "", err
+		}
+
+		var eq bool
+		if eq, err = equals(val, pairs[i+1]); err != nil {
+			return false, "", invalidParam(name, param)
+		}
+
+		match = match && eq
+		descs = append(descs, fmt.Sprintf("%s is %s", pairs[i], pairs[i+1]))
+	}
+	return match, strings.Join(descs, " and "), nil
+}
+
+func fieldPaths(name, param string) ([]string, error) {
+	paths := strings.Fields(param)
+	if len(paths) == 0 {
+		return nil, invalidParam(name, param)
+	}
+	return paths, nil
+}
+
+// Returns the value of the field at the dotted path relative to the struct value,
+// following pointers; fields of nil structs resolve to their zero value.
+func fieldByPath(val reflect.Value, path string) (reflect.Value, bool) {
+	for _, name := range strings.Split(path, ".") {
+		for val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				val = reflect.Zero(val.Type().Elem())
+				continue
+			}
+			val = val.Elem()
+		}
+
+		if val.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+
+		sf, ok := val.Type().FieldByName(name)
+		if !ok || !sf.IsExported() {
+			return reflect.Value{}, false
+		}
+
+		// Promoted fields of nil embedded struct pointers are zero valued.
+		field, err := val.FieldByIndexErr(sf.Index)
+		if err != nil {
+			field = reflect.Zero(sf.Type)
+		}
+		val = field
+	}
+	return val, true
+}
@@ -0,0 +1,154 @@
+package validate_test
+
+import (
+	"errors"
+	"testing"
+
+	"go.rtnl.ai/confire/assert"
+	confireErrors "go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/validate"
+)
+
+type TLSConfig struct {
+	Enabled  bool
+	CertFile string `validate:"required_if=Enabled true"`
+	KeyFile  string `validate:"required_with=CertFile"`
+	Insecure bool   `validate:"excluded_with=CertFile"`
+}
+
+type CrossFieldSpec struct {
+	Mode         string `validate:"oneof=dev cluster"`
+	Replicas     int    `validate:"required_unless=Mode dev"`
+	TLS          TLSConfig
+	Password     string `validate:"mutually_exclusive=PasswordFile"`
+	PasswordFile string `validate:"required_without=Password"`
+	CACert       string `validate:"required_if=TLS.Enabled true Mode cluster"`
+	Cluster      *ClusterConfig
+}
+
+type ClusterConfig struct {
+	Peers []string `validate:"required_if=Mode cluster"`
+}
+
+func TestCrossFieldValidators(t *testing.T) {
+	valid := &CrossFieldSpec{Mode: "dev", Password: "secret"}
+	assert.Ok(t, validate.Validate(valid))
+
+	valid = &CrossFieldSpec{
+		Mode:         "cluster",
+		Replicas:     3,
+		TLS:          TLSConfig{Enabled: true, CertFile: "cert.pem", KeyFile: "key.pem"},
+		PasswordFile: "/run/secrets/password",
+		CACert:       "ca.pem",
+		Cluster:      &ClusterConfig{Peers: []string{"a", "b"}},
+	}
+	assert.Ok(t, validate.Validate(valid))
+
+	testCases := []struct {
+		name  string
+		spec  *CrossFieldSpec
+		issue string
+	}{
+		{
+			"required_if sibling",
+			&CrossFieldSpec{Mode: "dev", Password: "secret", TLS: TLSConfig{Enabled: true, KeyFile: "key.pem"}},
+			"TLS.CertFile is required when Enabled is true",
+		},
+		{
+			"required_if dotted path",
+			&CrossFieldSpec{Mode: "cluster", Replicas: 3, Password: "secret", TLS: TLSConfig{Enabled: true, CertFile: "cert.pem", KeyFile: "key.pem"}, Cluster: &ClusterConfig{Peers: []string{"a"}}},
+			"CACert is required when TLS.Enabled is true and Mode is cluster",
+		},
+		{
+			"required_if root path",
+			&CrossFieldSpec{Mode: "cluster", Replicas: 3, Password: "secret"},
+			"Cluster.Peers is required when Mode is cluster",
+		},
+		{
+			"required_unless",
+			&CrossFieldSpec{Mode: "cluster", Password: "secret", Cluster: &ClusterConfig{Peers: []string{"a"}}},
+			"Replicas is required unless Mode is dev",
+		},
+		{
+			"required_with",
+			&CrossFieldSpec{Mode: "dev", Password: "secret", TLS: TLSConfig{CertFile: "cert.pem"}},
+			"TLS.KeyFile is required when CertFile is set",
+		},
+		{
+			"required_without",
+			&CrossFieldSpec{Mode: "dev"},
+			"PasswordFile is required when Password is not set",
+		},
+		{
+			"excluded_with",
+			&CrossFieldSpec{Mode: "dev", Password: "secret", TLS: TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", Insecure: true}},
+			"TLS.Insecure must not be set when CertFile is set",
+		},
+		{
+			"mutually_exclusive",
+			&CrossFieldSpec{Mode: "dev", Password: "secret", PasswordFile: "/run/secrets/password"},
+			"Password cannot be set with PasswordFile (only one of them may be set)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validate.Validate(tc.spec)
+			assert.NotOk(t, err)
+
+			var target *confireErrors.InvalidConfig
+			assert.True(t, errors.As(err, &target))
+			assert.Equals(t, "invalid configuration: "+tc.issue, err.Error())
+		})
+	}
+
+	// Conditionally required fields are missing required errors
+	err := validate.Validate(&CrossFieldSpec{Mode: "dev"})
+	assert.ErrorIs(t, err, confireErrors.ErrMissingRequired)
+}
+
+func TestCrossFieldErrors(t *testing.T) {
+	// Unknown fields and invalid parameters are errors in the specification that are
+	// returned when the validators are gathered rather than invalid configuration values.
+	testCases := []struct {
+		spec interface{}
+		err  string
+	}{
+		{&struct {
+			Cert string `validate:"required_with=Key"`
+		}{}, "Cert references unknown field \"Key\""},
+		{&struct {
+			Cert string `validate:"required_if=Nope true"`
+		}{}, "Cert references unknown field \"Nope\""},
+		{&struct {
+			TLS struct {
+				Cert string `validate:"mutually_exclusive=Key TLS.Nope"`
+				Key  string
+			}
+		}{}, "TLS.Cert references unknown field \"TLS.Nope\""},
+		{&struct {
+			Enabled bool
+			Cert    string `validate:"required_if=Enabled"`
+		}{}, "Cert has an invalid required_if parameter \"Enabled\""},
+		{&struct {
+			Enabled bool
+			Cert    string `validate:"required_unless=Enabled maybe"`
+		}{}, "Cert has an invalid required_unless parameter \"Enabled maybe\""},
+	}
+
+	for _, tc := range testCases {
+		_, err := validate.Gather(tc.spec)
+		assert.NotOk(t, err)
+		assert.Equals(t, tc.err, err.Error())
+
+		err = validate.Validate(tc.spec)
+		assert.Equals(t, tc.err, err.Error())
+
+		target := &confireErrors.InvalidConfig{}
+		assert.False(t, errors.As(err, &target))
+	}
+
+	// Cross-field validators cannot be replaced by registered validators
+	err := validate.Register("required_if", validateKafkaTopic)
+	assert.ErrorIs(t, err, confireErrors.ErrDuplicateValidator)
+}
@@ -0,0 +1,183 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+
+	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/structs"
+)
+
+// Markers in the validate tag that apply the rules that follow them to the elements of
+// slices, arrays, and maps (dive) or to the keys of maps (keys ... endkeys).
+const (
+	ruleDive    = "dive"
+	ruleKeys    = "keys"
+	ruleEndKeys = "endkeys"
+)
+
+// dive applies the rules that follow the dive marker of the validate tag to each element
+// of a slice, array, or map field, and the rules between keys and endkeys to each key of
+// a map field. Elements are named by their index, e.g. Endpoints[3] or Weights[alpha].
+type dive struct {
+	field *structs.Field
+	keys  []rule
+	elems []rule
+	scope scope
+}
+
+func (d dive) Validate() error {
+	var errs errors.ValidationErrors
+	switch d.field.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < d.field.Len(); i++ {
+			elem := d.field.Index(i).Named(fmt.Sprintf("%s[%d]", d.field.Name(), i))
+			errs = append(errs, d.validate(elem, d.elems)...)
+		}
+	case reflect.Map:
+		for _, key := range d.field.MapKeys() {
+			name := fmt.Sprintf("%s[%v]", d.field.Name(), key.Value())
+			if len(d.keys) > 0 {
+				errs = append(errs, d.validate(key.Named(name), d.keys)...)
+			}
+			errs = append(errs, d.validate(d.field.MapIndex(key).Named(name), d.elems)...)
+		}
+	}
+
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return errs
+	}
+}
+
+// Applies the rules to the element, returning the error of the first rule that fails as
+// is done for the rules of fields.
+func (d dive) validate(elem *structs.Field, rules []rule) errors.ValidationErrors {
+	for elem.Kind() == reflect.Ptr && !elem.IsNil() {
+		elem = elem.Elem()
+	}
+
+	validators, err := tagValidators(elem, rules, d.scope)
+	if err != nil {
+		return asValidationError(err, "", elem.Name())
+	}
+
+	for _, validator := range validators {
+		if err = validator.Validate(); err != nil {
+			return asValidationError(err, "", elem.Name())
+		}
+	}
+	return nil
+}
+
+// Returns the validators for the rules of the validate tag in order. The rules that
+// follow a dive marker are applied to the elements of the field by a dive validator.
+func tagValidators(field *structs.Field, rules []rule, sc scope) (validators []Validator, err error) {
+	for i, rule := range rules {
+		if rule.name == ruleDive {
+			d := dive{field: field, scope: sc}
+			d.keys, d.elems = splitKeys(rules[i+1:])
+			return append(validators, d), nil
+		}
+
+		// Cross-field validators are bound to the scope of the field.
+		if cross, ok := crossField[rule.name]; ok {
+			fn := func(field *structs.Field, param string) error { return cross(field, param, sc) }
+			validators = append(validators, tagged{field: field, fn: fn, param: rule.param})
+			continue
+		}
+
+		fn, ok := lookup(rule.name)
+		if !ok {
+			return nil, fmt.Errorf("unknown validator %q", rule.name)
+		}
+		validators = append(validators, tagged{field: field, fn: fn, param: rule.param})
+	}
+	return validators, nil
+}
+
+// Checks that the rules of the validate tag can be applied to the field at the path with
+// the type: all validators must be known, the parameters of the built-in validators must
+// be valid for the type, the fields referenced by cross-field validators must exist in
+// the scope, dive can only be applied to slices, arrays, and maps, and keys must
+// immediately follow dive on a map and be closed by endkeys.
+func checkRules(path string, typ reflect.Type, rules []rule, sc scope) error {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	for i, rule := range rules {
+		switch rule.name {
+		case ruleDive:
+			switch typ.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+			default:
+				return fmt.Errorf("cannot dive into %s: dive requires a slice, array, or map", typ)
+			}
+
+			rest := rules[i+1:]
+			if len(rest) > 0 && rest[0].name == ruleKeys {
+				if typ.Kind() != reflect.Map {
+					return fmt.Errorf("cannot validate the keys of %s: keys requires a map", typ)
+				}
+
+				end := -1
+				for j, r := range rest {
+					if r.name == ruleEndKeys {
+						end = j
+						break
+					}
+				}
+
+				if end < 0 {
+					return fmt.Errorf("keys must be closed by endkeys")
+				}
+
+				if err := checkRules(path, typ.Key(), rest[1:end], sc); err != nil {
+					return err
+				}
+				rest = rest[end+1:]
+			}
+			return checkRules(path, typ.Elem(), rest, sc)
+		case ruleKeys, ruleEndKeys:
+			return fmt.Errorf("%s must immediately follow dive", rule.name)
+		}
+
+		if _, ok := crossField[rule.name]; ok {
+			if err := sc.check(rule.name, rule.param); err != nil {
+				return fmt.Errorf("%s %w", path, err)
+			}
+			continue
+		}
+
+		if _, ok := lookup(rule.name); !ok {
+			return fmt.Errorf("unknown validator %q", rule.name)
+		}
+
+		// The types of interfaces are only known when the field is validated
+		if check, ok := checks[rule.name]; ok && typ.Kind() != reflect.Interface {
+			if err := check(typ, rule.param); err != nil {
+				return fmt.Errorf("%s %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Splits the rules that follow dive into the rules for map keys and for elements.
+func splitKeys(rules []rule) (keys, elems []rule) {
+	if len(rules) == 0 || rules[0].name != ruleKeys {
+		return nil, rules
+	}
+
+	for i, rule := range rules {
+		if rule.name == ruleEndKeys {
+			return rules[1:i], rules[i+1:]
+		}
+	}
+	return rules[1:], nil
+}
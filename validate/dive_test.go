@@ -0,0 +1,198 @@
+package validate_test
+
+import (
+	"errors"
+	"testing"
+
+	"go.rtnl.ai/confire/assert"
+	confireErrors "go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/validate"
+)
+
+func TestDive(t *testing.T) {
+	type Server struct {
+		Endpoints []string           `validate:"min=1,dive,url"`
+		Weights   map[string]float64 `validate:"dive,keys,hostname,endkeys,min=0,max=1"`
+		Ports     [2]int             `validate:"dive,min=1,max=65535"`
+		Aliases   []*string          `validate:"dive,required"`
+		Groups    [][]string         `validate:"dive,nonempty,dive,oneof=read write"`
+	}
+
+	type Specification struct {
+		Server Server
+	}
+
+	valid := &Specification{
+		Server: Server{
+			Endpoints: []string{"https://a.example.com", "https://b.example.com"},
+			Weights:   map[string]float64{"a.example.com": 0.75, "b.example.com": 0.25},
+			Ports:     [2]int{80, 443},
+			Groups:    [][]string{{"read"}, {"read", "write"}},
+		},
+	}
+	assert.Ok(t, validate.Validate(valid))
+
+	alias := "primary"
+	invalid := &Specification{
+		Server: Server{
+			Endpoints: []string{"https://a.example.com", "a.example.com", "https://c.example.com", "c.example.com"},
+			Weights:   map[string]float64{"a.example.com": 1.5, "-b-": 0.25},
+			Ports:     [2]int{80, 0},
+			Aliases:   []*string{&alias, nil},
+			Groups:    [][]string{{"read"}, {}, {"read", "delete"}},
+		},
+	}
+
+	err := validate.Validate(invalid)
+	assert.NotOk(t, err)
+
+	var target confireErrors.ValidationErrors
+	assert.True(t, errors.As(err, &target))
+
+	fields := make([]string, 0, len(target))
+	for _, verr := range target {
+		fields = append(fields, verr.Field())
+	}
+
+	expected := []string{
+		"Server.Endpoints[1]",
+		"Server.Endpoints[3]",
+		"Server.Weights[-b-]",
+		"Server.Weights[a.example.com]",
+		"Server.Ports[1]",
+		"Server.Aliases[1]",
+		"Server.Groups[1]",
+		"Server.Groups[2][1]",
+	}
+	assert.Equals(t, expected, fields)
+	assert.Equals(t, "invalid configuration: Server.Endpoints[3] must be a valid URL with a scheme and host, e.g. https://example.com", target[1].Error())
+	assert.Equals(t, "invalid configuration: Server.Weights[-b-] must be a valid hostname, e.g. example.com", target[2].Error())
+	assert.Equals(t, "invalid configuration: Server.Weights[a.example.com] must be at most 1", target[3].Error())
+	assert.Equals(t, "invalid configuration: Server.Aliases[1] is required but not set", target[5].Error())
+	assert.Equals(t, "invalid configuration: Server.Groups[2][1] must be one of read, write", target[7].Error())
+
+	// Validators before dive apply to the field as a whole
+	err = validate.Validate(&Specification{Server: Server{Ports: [2]int{80, 443}}})
+	assert.Equals(t, "invalid configuration: Server.Endpoints must have at least 1 elements", err.Error())
+}
+
+func TestDiveStructs(t *testing.T) {
+	type Specification struct {
+		Peers    []PeerConfig
+		Replicas [2]PeerConfig
+		Routes   map[string]PeerConfig
+		Backends map[string]*PeerConfig
+	}
+
+	valid := &Specification{
+		Peers:    []PeerConfig{{Host: "alpha"}},
+		Replicas: [2]PeerConfig{{Host: "bravo"}, {Host: "charlie"}},
+		Routes:   map[string]PeerConfig{"api": {Host: "delta"}},
+		Backends: map[string]*PeerConfig{"db": {Host: "echo"}, "cache": nil},
+	}
+	assert.Ok(t, validate.Validate(valid))
+
+	// Structs in slices, arrays, and maps are validated by their fields and Validators
+	invalid := &Specification{
+		Peers:    []PeerConfig{{Host: "localhost"}},
+		Replicas: [2]PeerConfig{{Host: "bravo"}},
+		Routes:   map[string]PeerConfig{"api": {Host: "localhost"}, "web": {}},
+		Backends: map[string]*PeerConfig{"db": {}},
+	}
+
+	err := validate.Validate(invalid)
+	assert.NotOk(t, err)
+
+	var target confireErrors.ValidationErrors
+	assert.True(t, errors.As(err, &target))
+
+	fields := make([]string, 0, len(target))
+	for _, verr := range target {
+		fields = append(fields, verr.Field())
+	}
+
+	expected := []string{"Peers[0].host", "Replicas[1].Host", "Routes[api].host", "Routes[web].Host", "Backends[db].Host"}
+	assert.Equals(t, expected, fields)
+}
+
+func TestDivePointerValidators(t *testing.T) {
+	type Specification struct {
+		Peers    []ReplicaConfig
+		Pointers []*ReplicaConfig
+		Replicas [1]ReplicaConfig
+		Routes   map[string]ReplicaConfig
+		Backends map[string]*ReplicaConfig
+	}
+
+	valid := &Specification{
+		Peers:    []ReplicaConfig{{Weight: 1}},
+		Pointers: []*ReplicaConfig{{Weight: 2}},
+		Replicas: [1]ReplicaConfig{{Weight: 3}},
+		Routes:   map[string]ReplicaConfig{"api": {Weight: 4}},
+		Backends: map[string]*ReplicaConfig{"db": {Weight: 5}},
+	}
+	assert.Ok(t, validate.Validate(valid))
+
+	// Validators with a pointer receiver are called on the elements
+	err := validate.Validate(&Specification{
+		Peers:    []ReplicaConfig{{}},
+		Pointers: []*ReplicaConfig{{}},
+		Replicas: [1]ReplicaConfig{{}},
+		Routes:   map[string]ReplicaConfig{"api": {}},
+		Backends: map[string]*ReplicaConfig{"db": {}},
+	})
+	assert.NotOk(t, err)
+
+	var target confireErrors.ValidationErrors
+	assert.True(t, errors.As(err, &target))
+
+	fields := make([]string, 0, len(target))
+	for _, verr := range target {
+		fields = append(fields, verr.Field())
+	}
+
+	expected := []string{"Peers[0].weight", "Pointers[0].weight", "Replicas[0].weight", "Routes[api].weight", "Backends[db].weight"}
+	assert.Equals(t, expected, fields)
+}
+
+type ReplicaConfig struct {
+	Weight int
+}
+
+func (r *ReplicaConfig) Validate() error {
+	if r.Weight < 1 {
+		return confireErrors.Invalid("", "weight", "must be positive")
+	}
+	return nil
+}
+
+func TestDiveErrors(t *testing.T) {
+	testCases := []struct {
+		spec interface{}
+		err  string
+	}{
+		{&struct {
+			Port int `validate:"dive,min=1"`
+		}{}, "cannot dive into int: dive requires a slice, array, or map"},
+		{&struct {
+			Peers []string `validate:"dive,keys,hostname,endkeys"`
+		}{}, "cannot validate the keys of []string: keys requires a map"},
+		{&struct {
+			Labels map[string]string `validate:"dive,keys,hostname"`
+		}{}, "keys must be closed by endkeys"},
+		{&struct {
+			Labels map[string]string `validate:"keys,hostname,endkeys"`
+		}{}, "keys must immediately follow dive"},
+		{&struct {
+			Peers []string `validate:"dive,notavalidator"`
+		}{}, "unknown validator \"notavalidator\""},
+	}
+
+	for _, tc := range testCases {
+		_, err := validate.Gather(tc.spec)
+		assert.Equals(t, tc.err, err.Error())
+	}
+
+	err := validate.Register("dive", validateKafkaTopic)
+	assert.NotOk(t, err)
+}
@@ -0,0 +1,78 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/structs"
+)
+
+// Func validates the field using the parameter of the validator from the validate tag,
+// e.g. 65535 for max=65535 (the parameter is empty if none is specified). It should
+// return an errors.InvalidConfig that describes what was expected if the field is
+// invalid.
+type Func func(field *structs.Field, param string) error
+
+var (
+	regmu    sync.RWMutex
+	registry = map[string]Func{
+		"required": validateRequired,
+		"min":      validateMin,
+		"max":      validateMax,
+		"len":      validateLen,
+		"oneof":    validateOneOf,
+		"regexp":   validateRegexp,
+		"url":      validateURL,
+		"hostname": validateHostname,
+		"hostport": validateHostPort,
+		"ip":       validateIP,
+		"cidr":     validateCIDR,
+		"email":    validateEmail,
+		"file":     validateFile,
+		"dir":      validateDir,
+		"absdir":   validateAbsDir,
+		"nonempty": validateNonEmpty,
+		"unique":   validateUnique,
+	}
+)
+
+// Register a validator that can be specified by name in the validate tag, e.g. for
+// domain specific validators such as validate:"kafka_topic". Validators are usually
+// registered in an init function; it is safe to register validators concurrently with
+// validation. An error is returned if the name is already registered (including the
+// names of the built-in and cross-field validators) or if the name cannot be used in a
+// validate tag.
+func Register(name string, fn func(field *structs.Field, param string) error) error {
+	if name == "" || strings.ContainsAny(name, ",= \t") || name == "ignore" || name == "ignored" ||
+		name == ruleDive || name == ruleKeys || name == ruleEndKeys {
+		return fmt.Errorf("confire: cannot register validator %q: invalid name", name)
+	}
+
+	if fn == nil {
+		return fmt.Errorf("confire: cannot register validator %q: nil validator", name)
+	}
+
+	regmu.Lock()
+	defer regmu.Unlock()
+
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("confire: cannot register validator %q: %w", name, errors.ErrDuplicateValidator)
+	}
+
+	if _, ok := crossField[name]; ok {
+		return fmt.Errorf("confire: cannot register validator %q: %w", name, errors.ErrDuplicateValidator)
+	}
+
+	registry[name] = fn
+	return nil
+}
+
+// Returns the validator registered with the name.
+func lookup(name string) (fn Func, ok bool) {
+	regmu.RLock()
+	defer regmu.RUnlock()
+	fn, ok = registry[name]
+	return fn, ok
+}
@@ -0,0 +1,101 @@
+package validate_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go.rtnl.ai/confire/assert"
+	confireErrors "go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/structs"
+	"go.rtnl.ai/confire/validate"
+)
+
+func init() {
+	if err := validate.Register("kafka_topic", validateKafkaTopic); err != nil {
+		panic(err)
+	}
+
+	if err := validate.Register("prefix", validatePrefix); err != nil {
+		panic(err)
+	}
+}
+
+func validateKafkaTopic(field *structs.Field, _ string) error {
+	topic, _ := field.Value().(string)
+	if topic == "" || len(topic) > 249 || strings.ContainsAny(topic, " /:") {
+		return confireErrors.Invalid("", field.Name(), "must be a valid kafka topic")
+	}
+	return nil
+}
+
+func validatePrefix(field *structs.Field, param string) error {
+	if s, _ := field.Value().(string); !strings.HasPrefix(s, param) {
+		return confireErrors.Invalid("", field.Name(), "must start with %q", param)
+	}
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	type Specification struct {
+		Topic  string `validate:"kafka_topic"`
+		Region string `validate:"nonempty,prefix=us-"`
+	}
+
+	err := validate.Validate(&Specification{Topic: "events", Region: "us-east-1"})
+	assert.Ok(t, err)
+
+	err = validate.Validate(&Specification{Topic: "my events", Region: "eu-west-1"})
+	assert.NotOk(t, err)
+
+	var target confireErrors.ValidationErrors
+	assert.True(t, errors.As(err, &target))
+	assert.Equals(t, 2, len(target))
+	assert.Equals(t, "invalid configuration: Topic must be a valid kafka topic", target[0].Error())
+	assert.Equals(t, "invalid configuration: Region must start with \"us-\"", target[1].Error())
+}
+
+func TestRegisterErrors(t *testing.T) {
+	// Duplicate names cannot be registered, including the names of built-in validators
+	for _, name := range []string{"kafka_topic", "required", "min", "url"} {
+		err := validate.Register(name, validateKafkaTopic)
+		assert.ErrorIs(t, err, confireErrors.ErrDuplicateValidator)
+	}
+
+	// Names that cannot be used in a validate tag cannot be registered
+	for _, name := range []string{"", "a,b", "a=b", "a b", "ignore", "ignored"} {
+		err := validate.Register(name, validateKafkaTopic)
+		assert.NotOk(t, err)
+	}
+
+	err := validate.Register("nil_validator", nil)
+	assert.NotOk(t, err)
+}
+
+var registered int64
+
+func TestRegisterConcurrency(t *testing.T) {
+	type Specification struct {
+		Topic string `validate:"required,kafka_topic"`
+		Port  int    `validate:"min=1"`
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("concurrent_%d", atomic.AddInt64(&registered, 1))
+			assert.Ok(t, validate.Register(name, validatePrefix))
+		}()
+
+		go func() {
+			defer wg.Done()
+			assert.Ok(t, validate.Validate(&Specification{Topic: "events", Port: 8080}))
+		}()
+	}
+	wg.Wait()
+}
@@ -16,7 +16,7 @@ type required struct {
 
 func (r required) Validate() error {
 	if r.field.IsZero() {
-		// TODO: add parent to the error
+		// The path of the parent is added to the error by Validate
 		return errors.Required("", r.field.Name())
 	}
 	return nil
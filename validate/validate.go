@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/parse"
 	"go.rtnl.ai/confire/structs"
 )
 
@@ -23,11 +24,13 @@ type Validator interface {
 
 // Validate runs the given struct through the validation workflow as follows: if the
 // required tag is set to true and the field is zero-valued then an error is returned.
-// Otherwise, if the field is a Validator its validate method is called. Finally if a
-// built-in validator is specified by the validate tag, then it is applied to the field
-// value. The invalid configuration is returned as a multi-error. If the validate tag
-// is set to ignored or the field has no required/validate tag and is not a Validator,
-// then no validation is applied to the field.
+// Otherwise, if the field is a Validator its validate method is called. Finally if
+// built-in validators are specified by the validate tag (comma-separated, with optional
+// parameters, e.g. min=1,max=65535), then they are applied to the field value in order.
+// The invalid configuration is returned as a multi-error whose fields are identified by
+// their dotted path, e.g. Database.Port. If the validate tag is set to ignored or the
+// field has no required/validate tag and is not a Validator, then no validation is
+// applied to the field.
 func Validate(spec interface{}) (err error) {
 	var infos []Info
 	if infos, err = Gather(spec); err != nil {
@@ -38,9 +41,11 @@ func Validate(spec interface{}) (err error) {
 	for _, info := range infos {
 		if verr := info.Validate.Validate(); verr != nil {
 			if info.Field != nil {
-				errs = append(errs, asValidationError(verr, info.Field.Name())...)
+				// Errors are reported within the struct that contains the field
+				conf := strings.TrimSuffix(strings.TrimSuffix(info.Path, info.Field.Name()), ".")
+				errs = append(errs, asValidationError(verr, conf, info.Field.Name())...)
 			} else {
-				errs = append(errs, asValidationError(verr, "")...)
+				errs = append(errs, structValidationError(verr, info.Path)...)
 			}
 		}
 	}
@@ -55,18 +60,27 @@ func Validate(spec interface{}) (err error) {
 	}
 }
 
+// Gather the validators of the fields of the specification and of its nested structs.
 func Gather(spec interface{}) (infos []Info, err error) {
+	return gather("", spec, reflect.ValueOf(spec))
+}
+
+// Gathers the validators of the spec at the dotted path, where root is the specification
+// being validated so that cross-field validators of nested structs can reference any
+// field by its path.
+func gather(path string, spec interface{}, root reflect.Value) (infos []Info, err error) {
 	var s *structs.Struct
 	if s, err = structs.New(spec); err != nil {
 		return nil, errors.ErrInvalidSpecification
 	}
+	sc := scope{root: root, parent: reflect.ValueOf(spec)}
 
 	// Create the infos to gather
 	infos = make([]Info, 0, s.NumField())
 
 	// If the spec implements the Validate method, add it to the infos
 	if validator, ok := ValidatorAs(s); ok && validator != nil {
-		infos = append(infos, Info{Validate: validator})
+		infos = append(infos, Info{Path: path, Validate: validator})
 	}
 
 	// Find validators for the fields
@@ -82,10 +96,10 @@ func Gather(spec interface{}) (infos []Info, err error) {
 		}
 
 		// Handle pointers if necessary
-		for field.Kind() == reflect.Pointer {
+		for field.Kind() == reflect.Pointer && !parse.Registered(field.Type()) {
 			if field.IsNil() {
-				if field.TypeKind() != reflect.Struct {
-					// nil pointer to a non-struct: leave it alone.
+				if field.TypeKind() != reflect.Struct || parse.IsDecodable(field) {
+					// nil pointer to a non-struct or to a decodable struct: leave it alone.
 					break
 				}
 
@@ -97,37 +111,98 @@ func Gather(spec interface{}) (infos []Info, err error) {
 			field = field.Elem()
 		}
 
-		// If this is a struct then gather validators for the nested fields
-		if field.Kind() == reflect.Struct {
+		fieldPath := join(path, field.Name())
+
+		// If this is a struct then gather validators for the nested fields unless it is
+		// a decodable type such as url.URL that is configured as a single value.
+		nested := field.Kind() == reflect.Struct && !parse.IsDecodable(field)
+		if nested {
+			// Embedded structs are referred to by their promoted field names
+			nestedPath := fieldPath
+			if field.IsEmbedded() {
+				nestedPath = path
+			}
+
 			var subinfos []Info
-			if subinfos, err = Gather(field.Pointer()); err != nil {
+			if subinfos, err = gather(nestedPath, field.Pointer(), root); err != nil {
 				return nil, err
 			}
 			infos = append(infos, subinfos...)
 		}
 
+		// If this is a slice or array of structs then gather validators for each element
+		if parse.IsStructSlice(field) || parse.IsStructArray(field) {
+			for i := 0; i < field.Len(); i++ {
+				elem := field.Index(i)
+				spec := elem.Value()
+				if elem.Kind() != reflect.Pointer {
+					spec = elem.Pointer()
+				}
+
+				var subinfos []Info
+				if subinfos, err = gather(fmt.Sprintf("%s[%d]", fieldPath, i), spec, root); err != nil {
+					return nil, err
+				}
+				infos = append(infos, subinfos...)
+			}
+		}
+
+		// If this is a map of structs then gather validators for each value
+		if parse.IsStructMap(field) {
+			for _, key := range field.MapKeys() {
+				elem := field.MapIndex(key)
+				spec := elem.Value()
+				switch {
+				case elem.Kind() != reflect.Pointer:
+					// Map values are not addressable so a copy of the struct is validated
+					ptr := reflect.New(elem.Type())
+					ptr.Elem().Set(elem.Reflect())
+					spec = ptr.Interface()
+				case elem.IsNil():
+					continue
+				}
+
+				var subinfos []Info
+				if subinfos, err = gather(fmt.Sprintf("%s[%v]", fieldPath, key.Value()), spec, root); err != nil {
+					return nil, err
+				}
+				infos = append(infos, subinfos...)
+			}
+		}
+
 		// Chain validators together if necessary
-		validators := make([]Validator, 0, 3)
+		validators := make([]Validator, 0, 4)
 
 		// Check if the required tag is set and add required validator if it is
 		if isTrue(field.Tag(tagRequired)) {
 			validators = append(validators, Required(field))
 		}
 
-		// Check if the field is a validator
-		if validator := ValidatorFrom(field); validator != nil {
-			validators = append(validators, validator)
+		// Check if the field is a validator; nested structs are validators of the struct
+		// that is gathered above so they are not validated a second time.
+		if !nested {
+			if validator := ValidatorFrom(field); validator != nil {
+				if field.Kind() == reflect.Struct {
+					// Errors of decodable structs are reported within the field
+					validator = structValidator{Validator: validator, name: field.Name()}
+				}
+				validators = append(validators, validator)
+			}
 		}
 
 		// Check if there is a validator tag
-		if validator := field.Tag(tagValidator); validator != "" {
-			// Lookup the specified validator in the validation library.
-			switch validator {
-			case "required":
-				validators = append(validators, Required(field))
-			default:
-				return nil, fmt.Errorf("unknown validator %q", validator)
+		if tag := field.Tag(tagValidator); tag != "" {
+			// Lookup the specified validators in the validation library.
+			rules := parseRules(tag)
+			if err = checkRules(fieldPath, field.Type(), rules, sc); err != nil {
+				return nil, err
 			}
+
+			var tagged []Validator
+			if tagged, err = tagValidators(field, rules, sc); err != nil {
+				return nil, err
+			}
+			validators = append(validators, tagged...)
 		}
 
 		// If no validators were specified by the user, ignore this field
@@ -135,7 +210,7 @@ func Gather(spec interface{}) (infos []Info, err error) {
 			continue
 		}
 
-		info := Info{Field: field}
+		info := Info{Field: field, Path: fieldPath}
 		if len(validators) == 1 {
 			info.Validate = validators[0]
 		} else {
@@ -150,6 +225,7 @@ func Gather(spec interface{}) (infos []Info, err error) {
 
 type Info struct {
 	Field    *structs.Field // The actual field to get the validation info from (along with tags)
+	Path     string         // The dotted path of the field (or of the struct if Field is nil)
 	Validate Validator      // The validator to apply to the field
 }
 
@@ -159,10 +235,11 @@ func ValidatorFrom(field *structs.Field) (v Validator) {
 	return v
 }
 
-// Attempts to get a Validator variable from the specified struct.
+// Attempts to get a Validator variable from the specified struct. If the struct was
+// specified by a pointer, methods with a pointer receiver are found as well.
 func ValidatorAs(s *structs.Struct) (Validator, bool) {
-	if s.Implements((*Validator)(nil)) {
-		return s.Interface().(Validator), true
+	if validator, ok := s.Interface().(Validator); ok {
+		return validator, true
 	}
 	return nil, false
 }
@@ -180,18 +257,96 @@ func ignoreValidation(s string) bool {
 	return false
 }
 
-func asValidationError(err error, source string) errors.ValidationErrors {
+// Converts the error into validation errors within the configuration at the dotted path.
+func asValidationError(err error, conf, source string) errors.ValidationErrors {
 	out := make(errors.ValidationErrors, 0, 1)
 
 	target := &errors.InvalidConfig{}
 	if goerrors.As(err, &target) {
-		return append(out, target)
+		return append(out, target.Within(conf))
 	}
 
 	if errs, ok := err.(errors.ValidationErrors); ok {
-		out = append(out, errs...)
+		for _, e := range errs {
+			out = append(out, e.Within(conf))
+		}
 		return out
 	}
 
-	return append(out, errors.Wrap("", source, err.Error(), err))
+	return append(out, errors.Wrap(conf, source, err.Error(), err))
+}
+
+// structValidator qualifies the errors of the Validate method of a struct field that is
+// not gathered as a nested struct (e.g. because it is decodable) with the field's name.
+type structValidator struct {
+	Validator
+	name string
+}
+
+func (v structValidator) Validate() error {
+	err := v.Validator.Validate()
+	if err == nil {
+		return nil
+	}
+
+	errs := structValidationError(err, v.name)
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errs
+}
+
+// Converts the error returned by the Validate method of the struct at the dotted path
+// into validation errors. Errors that do not name the configuration they belong to are
+// reported within the struct, e.g. port is reported as Database.port, while errors that
+// do name it, e.g. ui.palette, are reported within the parent of the struct.
+func structValidationError(err error, path string) errors.ValidationErrors {
+	parent, name := split(path)
+
+	var errs errors.ValidationErrors
+	target := &errors.InvalidConfig{}
+	if goerrors.As(err, &target) {
+		errs = errors.ValidationErrors{target}
+	} else if verrs, ok := err.(errors.ValidationErrors); ok {
+		errs = verrs
+	} else {
+		return errors.ValidationErrors{errors.Wrap(parent, name, err.Error(), err)}
+	}
+
+	out := make(errors.ValidationErrors, 0, len(errs))
+	for _, e := range errs {
+		if e.Conf() == "" {
+			out = append(out, e.Within(path))
+		} else {
+			out = append(out, e.Within(parent))
+		}
+	}
+	return out
+}
+
+// Joins the field name to the dotted path of its parent.
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// Splits the dotted path into the path of its parent and its last name, ignoring any
+// dots inside of the keys of map elements, e.g. Peers[a.b].Host is split after ].
+func split(path string) (parent, name string) {
+	depth := 0
+	for i := len(path) - 1; i >= 0; i-- {
+		switch path[i] {
+		case ']':
+			depth++
+		case '[':
+			depth--
+		case '.':
+			if depth == 0 {
+				return path[:i], path[i+1:]
+			}
+		}
+	}
+	return "", path
 }
@@ -169,6 +169,38 @@ func TestNestedPointerRequired(t *testing.T) {
 	assert.Ok(t, err)
 }
 
+func TestSliceRequired(t *testing.T) {
+	type Specification struct {
+		Nested   []NestedRequired
+		Pointers []*NestedRequired
+	}
+
+	valid := &Specification{}
+	err := validate.Validate(valid)
+	assert.Ok(t, err)
+
+	// Each element of the slice is validated
+	invalid := &Specification{Nested: []NestedRequired{{PropA: "foo", PropB: 32}, {PropA: "bar"}}}
+	err = validate.Validate(invalid)
+	assert.Assert(t, err != nil, "expected a validation error to have occurred")
+	assert.ErrorIs(t, err, confireErrors.ErrMissingRequired)
+
+	invalid = &Specification{Pointers: []*NestedRequired{{PropA: "foo", PropB: 32}, nil}}
+	err = validate.Validate(invalid)
+	assert.Assert(t, err != nil, "expected a validation error to have occurred")
+
+	var target confireErrors.ValidationErrors
+	assert.True(t, errors.As(err, &target))
+	assert.Equals(t, 2, len(target))
+
+	valid = &Specification{
+		Nested:   []NestedRequired{{PropA: "foo", PropB: 32}, {PropA: "bar", PropB: 64}},
+		Pointers: []*NestedRequired{{PropA: "baz", PropB: 128}},
+	}
+	err = validate.Validate(valid)
+	assert.Ok(t, err)
+}
+
 func TestRequiredTypes(t *testing.T) {
 	type Specification struct {
 		Ptr       *string        `required:"true"`
@@ -251,3 +283,110 @@ func (a Age) Validate() error {
 }
 
 var _ validate.Validator = Age(1)
+
+func TestValidationPaths(t *testing.T) {
+	type Server struct {
+		Port int `validate:"min=1"`
+	}
+
+	type Embedded struct {
+		Region string `required:"true"`
+	}
+
+	type Specification struct {
+		Embedded
+		Server   Server
+		Database *Server
+		Peers    []PeerConfig
+	}
+
+	err := validate.Validate(&Specification{Peers: []PeerConfig{{Host: "alpha"}, {}, {Host: "localhost"}}})
+	assert.NotOk(t, err)
+
+	var target confireErrors.ValidationErrors
+	assert.True(t, errors.As(err, &target))
+
+	fields := make([]string, 0, len(target))
+	for _, verr := range target {
+		fields = append(fields, verr.Field())
+	}
+
+	// Nested fields are identified by their dotted path and embedded fields by their
+	// promoted names; errors from Validators are reported within their struct.
+	expected := []string{"Region", "Server.Port", "Database.Port", "Peers[1].Host", "Peers[2].host"}
+	assert.Equals(t, expected, fields)
+	assert.Equals(t, "invalid configuration: Peers[1].Host is required but not set", target[3].Error())
+
+	infos, err := validate.Gather(&Specification{})
+	assert.Ok(t, err)
+	assert.Equals(t, "Region", infos[0].Path)
+	assert.Equals(t, "Server.Port", infos[1].Path)
+}
+
+func TestNestedValidators(t *testing.T) {
+	type Specification struct {
+		Server   ServerConfig
+		Database *ServerConfig
+		Listen   Endpoint
+	}
+
+	calls = 0
+	err := validate.Validate(&Specification{Listen: Endpoint{Host: "localhost"}})
+	assert.NotOk(t, err)
+
+	var target confireErrors.ValidationErrors
+	assert.True(t, errors.As(err, &target))
+
+	fields := make([]string, 0, len(target))
+	for _, verr := range target {
+		fields = append(fields, verr.Field())
+	}
+
+	// Errors of nested Validators are reported within their struct and each Validator
+	// is only called once.
+	assert.Equals(t, []string{"Server.port", "Database.port", "Listen.host"}, fields)
+	assert.Equals(t, 2, calls)
+}
+
+// Counts the calls to the Validate method of ServerConfig.
+var calls int
+
+type ServerConfig struct {
+	Port int
+}
+
+func (s ServerConfig) Validate() error {
+	calls++
+	if s.Port < 1 {
+		return confireErrors.Invalid("", "port", "must be positive")
+	}
+	return nil
+}
+
+// Endpoint is a decodable struct so it is validated as a single value.
+type Endpoint struct {
+	Host string
+}
+
+func (e *Endpoint) Decode(value string) error {
+	e.Host = value
+	return nil
+}
+
+func (e Endpoint) Validate() error {
+	if e.Host == "localhost" {
+		return confireErrors.Invalid("", "host", "must not be a loopback address")
+	}
+	return nil
+}
+
+type PeerConfig struct {
+	Host string `required:"true"`
+}
+
+func (p PeerConfig) Validate() error {
+	if p.Host == "localhost" {
+		return confireErrors.Invalid("", "host", "must not be a loopback address")
+	}
+	return nil
+}
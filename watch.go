@@ -0,0 +1,238 @@
+package confire
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/file"
+	"go.rtnl.ai/confire/validate"
+)
+
+// DefaultWatchInterval is how often the configuration file is checked for changes.
+const DefaultWatchInterval = 5 * time.Second
+
+// Watcher reprocesses a configuration when one of its configuration files changes or
+// when the process receives a SIGHUP signal (on platforms that support it). The files
+// that are watched are those loaded by the file sources of the pipeline, including any
+// file.Source specified by WithSources. Each time the configuration is reloaded, the
+// full pipeline of sources is processed into a new candidate specification which is
+// only swapped in if it is valid, so the current configuration is always valid.
+type Watcher struct {
+	prefix   string
+	opts     []Option
+	opt      *options
+	typ      reflect.Type
+	signals  chan os.Signal
+	done     chan struct{}
+	closing  sync.Once
+	wg       sync.WaitGroup
+	reload   sync.Mutex
+	stamp    [sha256.Size]byte
+	mu       sync.RWMutex
+	current  interface{}
+	origins  Origins
+	handlers []func(prev, next interface{})
+	errs     []func(error)
+}
+
+// Watch processes the specification and then watches for changes to the configuration
+// file (by polling its contents) and for SIGHUP signals, reprocessing the configuration
+// when either occurs. The specification passed in holds the initial configuration; use
+// Current to get the most recently loaded configuration, which is a new pointer of the
+// same type as the spec. Close must be called to stop watching.
+//
+// If WithOrigins is specified, the origins of the initial configuration are recorded in
+// the map passed to it; each reload records its origins in a new map so that the map
+// passed to WithOrigins is never modified after Watch returns. Use Origins to get the
+// origins of the current configuration.
+func Watch(prefix string, spec interface{}, opts ...Option) (w *Watcher, err error) {
+	var opt *options
+	if opt, err = makeOptions(opts...); err != nil {
+		return nil, err
+	}
+
+	typ := reflect.TypeOf(spec)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+		return nil, errors.ErrInvalidSpecification
+	}
+
+	w = &Watcher{
+		prefix:  prefix,
+		opts:    opts,
+		opt:     opt,
+		typ:     typ.Elem(),
+		signals: make(chan os.Signal, 1),
+		done:    make(chan struct{}),
+	}
+
+	w.stamp = w.fingerprint()
+	if err = w.process(spec, opt.origins); err != nil {
+		return nil, err
+	}
+	w.current = spec
+	w.origins = opt.origins
+
+	notifyReload(w.signals)
+	w.wg.Add(1)
+	go w.run()
+	return w, nil
+}
+
+// Current returns the most recently loaded configuration.
+func (w *Watcher) Current() interface{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Origins returns the origins of the values of the current configuration, or nil if the
+// WithOrigins option was not specified.
+func (w *Watcher) Origins() Origins {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.origins
+}
+
+// Subscribe registers a callback that is called with the previous and next
+// configuration each time the configuration is successfully reloaded.
+func (w *Watcher) Subscribe(fn func(prev, next interface{})) {
+	w.mu.Lock()
+	w.handlers = append(w.handlers, fn)
+	w.mu.Unlock()
+}
+
+// OnError registers a callback that is called when the configuration cannot be
+// reloaded because it could not be processed or is invalid.
+func (w *Watcher) OnError(fn func(error)) {
+	w.mu.Lock()
+	w.errs = append(w.errs, fn)
+	w.mu.Unlock()
+}
+
+// Reload reprocesses the configuration immediately, swapping in the new configuration
+// and notifying subscribers if it is valid. An error is returned if the configuration
+// could not be processed or is invalid, in which case the current config is unchanged.
+func (w *Watcher) Reload() (err error) {
+	w.reload.Lock()
+	defer w.reload.Unlock()
+
+	var origins Origins
+	if w.opt.origins != nil {
+		origins = make(Origins)
+	}
+
+	next := reflect.New(w.typ).Interface()
+	if err = w.process(next, origins); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	prev := w.current
+	w.current = next
+	w.origins = origins
+	handlers := append(make([]func(prev, next interface{}), 0, len(w.handlers)), w.handlers...)
+	w.mu.Unlock()
+
+	for _, fn := range handlers {
+		fn(prev, next)
+	}
+	return nil
+}
+
+// Close stops watching for changes to the configuration. It is safe to call Close more
+// than once.
+func (w *Watcher) Close() error {
+	w.closing.Do(func() {
+		stopReload(w.signals)
+		close(w.done)
+	})
+	w.wg.Wait()
+	return nil
+}
+
+func (w *Watcher) run() {
+	defer w.wg.Done()
+
+	interval := w.opt.watchInterval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.signals:
+			w.stamp = w.fingerprint()
+			w.reloadAndReport()
+		case <-ticker.C:
+			if stamp := w.fingerprint(); stamp != w.stamp {
+				w.stamp = stamp
+				w.reloadAndReport()
+			}
+		}
+	}
+}
+
+func (w *Watcher) reloadAndReport() {
+	if err := w.Reload(); err != nil {
+		w.mu.RLock()
+		errs := append(make([]func(error), 0, len(w.errs)), w.errs...)
+		w.mu.RUnlock()
+
+		for _, fn := range errs {
+			fn(err)
+		}
+	}
+}
+
+// Process the candidate with all of the sources and validate it, regardless of the
+// NoValidate option, so that only valid configurations are swapped in. The origins of
+// the candidate are recorded in the specified map rather than the WithOrigins map.
+func (w *Watcher) process(spec interface{}, origins Origins) (err error) {
+	opts := append(append(make([]Option, 0, len(w.opts)+2), w.opts...), NoValidate, WithOrigins(origins))
+	if err = ProcessContext(context.Background(), w.prefix, spec, opts...); err != nil {
+		return err
+	}
+	return validate.Validate(spec)
+}
+
+// Returns a hash of the paths and contents of the configuration files loaded by the
+// pipeline so that changes are detected even if the modification time does not change.
+// If there are no configuration files, the hash of no paths is returned.
+func (w *Watcher) fingerprint() [sha256.Size]byte {
+	h := sha256.New()
+	for _, path := range w.paths() {
+		data, _ := os.ReadFile(path)
+		h.Write([]byte(path))
+		h.Write([]byte{0})
+		h.Write(data)
+		h.Write([]byte{0})
+	}
+
+	var stamp [sha256.Size]byte
+	copy(stamp[:], h.Sum(nil))
+	return stamp
+}
+
+// Returns the paths of the configuration files that are loaded by the file sources of
+// the pipeline in the order that they are loaded.
+func (w *Watcher) paths() (paths []string) {
+	for _, src := range w.opt.pipeline(w.prefix) {
+		switch src := src.(type) {
+		case file.Source:
+			paths = append(paths, src.Path)
+		case *file.Source:
+			paths = append(paths, src.Path)
+		}
+	}
+	return paths
+}
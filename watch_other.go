@@ -0,0 +1,11 @@
+//go:build !unix
+
+package confire
+
+import "os"
+
+// SIGHUP is not available on this platform so the watcher only reloads the
+// configuration when the configuration file changes or Reload is called.
+func notifyReload(c chan<- os.Signal) {}
+
+func stopReload(c chan<- os.Signal) {}
@@ -0,0 +1,183 @@
+package confire_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/confire"
+	"go.rtnl.ai/confire/assert"
+	"go.rtnl.ai/confire/defaults"
+	"go.rtnl.ai/confire/errors"
+	"go.rtnl.ai/confire/file"
+)
+
+type WatchConfig struct {
+	LogLevel  string `default:"info" split_words:"true"`
+	RateLimit int    `default:"100" split_words:"true"`
+}
+
+func (c WatchConfig) Validate() error {
+	if c.RateLimit < 1 {
+		return confire.Invalid("", "rateLimit", "must be positive")
+	}
+	return nil
+}
+
+type changes struct {
+	sync.Mutex
+	events []*WatchConfig
+	errs   []error
+	notify chan struct{}
+}
+
+func (c *changes) subscribe(w *confire.Watcher) {
+	c.notify = make(chan struct{}, 16)
+	w.Subscribe(func(prev, next interface{}) {
+		c.Lock()
+		c.events = append(c.events, next.(*WatchConfig))
+		c.Unlock()
+		c.notify <- struct{}{}
+	})
+
+	w.OnError(func(err error) {
+		c.Lock()
+		c.errs = append(c.errs, err)
+		c.Unlock()
+		c.notify <- struct{}{}
+	})
+}
+
+func (c *changes) wait(t *testing.T) {
+	select {
+	case <-c.notify:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the configuration to reload")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	t.Setenv("CONFIRE_CONFIG", "")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Ok(t, os.WriteFile(path, []byte("log_level: debug\n"), 0600))
+
+	conf := &WatchConfig{}
+	w, err := confire.Watch("confire", conf, confire.WithConfigFile(path), confire.WithWatchInterval(10*time.Millisecond))
+	assert.Ok(t, err)
+	defer w.Close()
+
+	c := &changes{}
+	c.subscribe(w)
+
+	// The spec holds the initial configuration
+	assert.Equals(t, &WatchConfig{LogLevel: "debug", RateLimit: 100}, conf)
+	assert.Equals(t, conf, w.Current())
+
+	// Changing the file reloads the configuration
+	assert.Ok(t, os.WriteFile(path, []byte("log_level: warn\nrate_limit: 10\n"), 0600))
+	c.wait(t)
+
+	assert.Equals(t, &WatchConfig{LogLevel: "warn", RateLimit: 10}, w.Current())
+	assert.Equals(t, "debug", conf.LogLevel)
+
+	// An invalid configuration is not swapped in
+	assert.Ok(t, os.WriteFile(path, []byte("log_level: error\nrate_limit: -1\n"), 0600))
+	c.wait(t)
+
+	assert.Equals(t, &WatchConfig{LogLevel: "warn", RateLimit: 10}, w.Current())
+
+	c.Lock()
+	assert.Equals(t, 1, len(c.events))
+	assert.Equals(t, 1, len(c.errs))
+	assert.True(t, confire.IsInvalidConfig(c.errs[0]))
+	c.Unlock()
+}
+
+func TestWatchSources(t *testing.T) {
+	// Files loaded by the file sources specified with WithSources are watched
+	dir := t.TempDir()
+	base, override := filepath.Join(dir, "base.yaml"), filepath.Join(dir, "override.yaml")
+	assert.Ok(t, os.WriteFile(base, []byte("log_level: debug\n"), 0600))
+	assert.Ok(t, os.WriteFile(override, []byte("rate_limit: 10\n"), 0600))
+
+	conf := &WatchConfig{}
+	sources := confire.WithSources(defaults.Source{}, file.Source{Path: base}, &file.Source{Path: override})
+	w, err := confire.Watch("confire", conf, sources, confire.WithWatchInterval(10*time.Millisecond))
+	assert.Ok(t, err)
+	defer w.Close()
+
+	c := &changes{}
+	c.subscribe(w)
+	assert.Equals(t, &WatchConfig{LogLevel: "debug", RateLimit: 10}, conf)
+
+	assert.Ok(t, os.WriteFile(override, []byte("rate_limit: 20\n"), 0600))
+	c.wait(t)
+	assert.Equals(t, &WatchConfig{LogLevel: "debug", RateLimit: 20}, w.Current())
+
+	assert.Ok(t, os.WriteFile(base, []byte("log_level: warn\n"), 0600))
+	c.wait(t)
+	assert.Equals(t, &WatchConfig{LogLevel: "warn", RateLimit: 20}, w.Current())
+}
+
+func TestWatchReload(t *testing.T) {
+	conf := &WatchConfig{}
+	w, err := confire.Watch("confire", conf, confire.WithWatchInterval(time.Hour))
+	assert.Ok(t, err)
+	defer w.Close()
+
+	var prev, next interface{}
+	w.Subscribe(func(p, n interface{}) { prev, next = p, n })
+
+	t.Setenv("CONFIRE_LOG_LEVEL", "trace")
+	assert.Ok(t, w.Reload())
+	assert.Equals(t, conf, prev)
+	assert.Equals(t, &WatchConfig{LogLevel: "trace", RateLimit: 100}, next)
+	assert.Equals(t, next, w.Current())
+
+	// Errors are returned and the current config is unchanged
+	t.Setenv("CONFIRE_RATE_LIMIT", "notanumber")
+	assert.NotOk(t, w.Reload())
+	assert.Equals(t, next, w.Current())
+}
+
+func TestWatchOrigins(t *testing.T) {
+	origins := make(confire.Origins)
+	w, err := confire.Watch("confire", &WatchConfig{}, confire.WithWatchInterval(time.Hour), confire.WithOrigins(origins))
+	assert.Ok(t, err)
+	defer w.Close()
+
+	assert.Equals(t, confire.Origin{Kind: "default", Value: "info"}, origins["LogLevel"])
+	assert.Equals(t, origins, w.Origins())
+
+	// Reloads record their origins in a new map
+	t.Setenv("CONFIRE_LOG_LEVEL", "trace")
+	assert.Ok(t, w.Reload())
+	assert.Equals(t, confire.Origin{Kind: "default", Value: "info"}, origins["LogLevel"])
+	assert.Equals(t, confire.Origin{Kind: "env", Location: "CONFIRE_LOG_LEVEL", Value: "trace"}, w.Origins()["LogLevel"])
+
+	// Without WithOrigins no origins are recorded
+	w2, err := confire.Watch("confire", &WatchConfig{}, confire.WithWatchInterval(time.Hour))
+	assert.Ok(t, err)
+	defer w2.Close()
+	assert.Ok(t, w2.Reload())
+	assert.True(t, w2.Origins() == nil)
+}
+
+func TestWatchClose(t *testing.T) {
+	w, err := confire.Watch("confire", &WatchConfig{}, confire.WithWatchInterval(time.Hour))
+	assert.Ok(t, err)
+	assert.Ok(t, w.Close())
+	assert.Ok(t, w.Close())
+}
+
+func TestWatchErrors(t *testing.T) {
+	_, err := confire.Watch("confire", WatchConfig{})
+	assert.ErrorIs(t, err, errors.ErrInvalidSpecification)
+
+	// The initial configuration must be valid
+	t.Setenv("CONFIRE_RATE_LIMIT", "0")
+	_, err = confire.Watch("confire", &WatchConfig{})
+	assert.True(t, confire.IsInvalidConfig(err))
+}
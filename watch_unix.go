@@ -0,0 +1,18 @@
+//go:build unix
+
+package confire
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Relays SIGHUP signals to the channel so that the watcher reloads the configuration.
+func notifyReload(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGHUP)
+}
+
+func stopReload(c chan<- os.Signal) {
+	signal.Stop(c)
+}
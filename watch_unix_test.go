@@ -0,0 +1,31 @@
+//go:build unix
+
+package confire_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/confire"
+	"go.rtnl.ai/confire/assert"
+)
+
+func TestWatchSignal(t *testing.T) {
+	conf := &WatchConfig{}
+	w, err := confire.Watch("confire", conf, confire.WithWatchInterval(time.Hour))
+	assert.Ok(t, err)
+	defer w.Close()
+
+	c := &changes{}
+	c.subscribe(w)
+
+	// A SIGHUP reprocesses the environment
+	t.Setenv("CONFIRE_RATE_LIMIT", "42")
+	assert.Ok(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	c.wait(t)
+
+	assert.Equals(t, &WatchConfig{LogLevel: "info", RateLimit: 42}, w.Current())
+	assert.Equals(t, 100, conf.RateLimit)
+}